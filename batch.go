@@ -0,0 +1,357 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// GameResult is the outcome of one game of a batch
+type GameResult struct {
+	Run             int        `json:"run"`
+	Seed            int64      `json:"seed"`
+	Steps           int        `json:"steps"`
+	Reason          EndReason  `json:"reason"`
+	CitiesDestroyed int        `json:"cities_destroyed"`
+	Survivors       int        `json:"surviving_monsters"`
+	Trapped         int        `json:"trapped_monsters"`
+	SpawnDestroyed  int        `json:"destroyed_at_spawn"` // Cities destroyed while the monsters were being placed
+	LargestRegion   int        `json:"largest_region"`     // Cities in the largest connected group left standing
+	Destroyed       []CityName `json:"destroyed"`
+}
+
+// Distribution summarises the values of a measure across many games
+type Distribution struct {
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"`
+	Min    float64 `json:"min"`
+	P5     float64 `json:"p5"`
+	P25    float64 `json:"p25"`
+	Median float64 `json:"median"`
+	P75    float64 `json:"p75"`
+	P95    float64 `json:"p95"`
+	Max    float64 `json:"max"`
+}
+
+// CityDestruction is how often a city was destroyed across a batch of games
+type CityDestruction struct {
+	City      CityName `json:"city"`
+	Destroyed int      `json:"destroyed"`
+	Rate      float64  `json:"rate"`
+}
+
+// BatchReport aggregates the results of a batch of games played on the same map with the same number of monsters
+type BatchReport struct {
+	Runs            int               `json:"runs"`
+	Monsters        uint              `json:"monsters"`
+	Seed            int64             `json:"seed"`
+	CitiesDestroyed Distribution      `json:"cities_destroyed"`
+	Survivors       Distribution      `json:"surviving_monsters"`
+	Trapped         Distribution      `json:"trapped_monsters"`
+	Steps           Distribution      `json:"steps"`
+	LargestRegion   Distribution      `json:"largest_region"`
+	Cities          []CityDestruction `json:"cities"` // Every city of the map, in map order
+	Results         []GameResult      `json:"results"`
+}
+
+// BatchConfig describes a batch of games
+type BatchConfig struct {
+	Runs          int
+	Monsters      uint
+	Seed          int64 // Seeds of the individual games are derived from this one
+	MaxIterations int
+	Termination   TerminationRule
+	Strategies    []MovementStrategy // Given to the monsters in turn, uniform if empty
+	Combat        CombatRule         // Annihilation if nil
+	Mix           SpawnMix           // Species given to the monsters, none if empty
+	StepMode      StepMode
+	Workers       int // Number of games played at once
+}
+
+// deriveSeeds works out the seed of each game of a batch from the batch's seed, so that any game can be replayed on its own
+func deriveSeeds(seed int64, runs int) []int64 {
+	r := rand.New(rand.NewSource(seed))
+	seeds := make([]int64, runs)
+	for i := range seeds {
+		seeds[i] = r.Int63()
+	}
+	return seeds
+}
+
+// PlayBatch plays a batch of games on copies of the world, several at a time, and reports on them
+// The world itself is left untouched
+func PlayBatch(w *World, config BatchConfig) *BatchReport {
+	seeds := deriveSeeds(config.Seed, config.Runs)
+	results := make([]GameResult, config.Runs)
+
+	runs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < maxInt(config.Workers, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for run := range runs {
+				results[run] = playBatchGame(w.Copy(), config, run, seeds[run])
+			}
+		}()
+	}
+	for run := range results {
+		runs <- run
+	}
+	close(runs)
+	wg.Wait()
+
+	return summariseBatch(w, config, results)
+}
+
+// playBatchGame plays one game of a batch to completion on its own copy of the world
+func playBatchGame(w *World, config BatchConfig, run int, seed int64) GameResult {
+	result := GameResult{Run: run, Seed: seed, Destroyed: []CityName{}}
+	observer := GameObserverFunc(func(event GameEvent) {
+		switch e := event.(type) {
+		case CityDestroyed:
+			result.Destroyed = append(result.Destroyed, e.City)
+			if e.Step == 0 {
+				result.SpawnDestroyed++
+			}
+		case GameEnded:
+			result.Steps = e.Step
+			result.Reason = e.Reason
+			result.Survivors = len(e.Survivors)
+			for _, survivor := range e.Survivors {
+				if survivor.Trapped {
+					result.Trapped++
+				}
+			}
+		}
+	})
+	opts := []GameOption{WithSeed(seed), WithTermination(config.Termination), WithStrategies(config.Strategies...),
+		WithStepMode(config.StepMode), WithObserver(observer)}
+	if config.Combat != nil {
+		opts = append(opts, WithCombat(config.Combat))
+	}
+	if config.Mix != nil {
+		opts = append(opts, WithSpawnMix(config.Mix))
+	}
+	game := NewMonsterGame(w, config.MaxIterations, config.Monsters, opts...)
+	game.Start()
+	result.CitiesDestroyed = len(result.Destroyed)
+	result.LargestRegion = w.LargestSurvivingRegion()
+	return result
+}
+
+// summariseBatch works out the distributions of the results of a batch
+func summariseBatch(w *World, config BatchConfig, results []GameResult) *BatchReport {
+	report := &BatchReport{
+		Runs:     config.Runs,
+		Monsters: config.Monsters,
+		Seed:     config.Seed,
+		Results:  results,
+	}
+	measure := func(value func(GameResult) int) Distribution {
+		values := make([]float64, len(results))
+		for i, result := range results {
+			values[i] = float64(value(result))
+		}
+		return NewDistribution(values)
+	}
+	report.CitiesDestroyed = measure(func(r GameResult) int { return r.CitiesDestroyed })
+	report.Survivors = measure(func(r GameResult) int { return r.Survivors })
+	report.Trapped = measure(func(r GameResult) int { return r.Trapped })
+	report.Steps = measure(func(r GameResult) int { return r.Steps })
+	report.LargestRegion = measure(func(r GameResult) int { return r.LargestRegion })
+
+	destroyed := make(map[CityName]int)
+	for _, result := range results {
+		for _, city := range result.Destroyed {
+			destroyed[city]++
+		}
+	}
+	for _, city := range w.GetCities() {
+		cd := CityDestruction{City: city.Name, Destroyed: destroyed[city.Name]}
+		if config.Runs > 0 {
+			cd.Rate = float64(cd.Destroyed) / float64(config.Runs)
+		}
+		report.Cities = append(report.Cities, cd)
+	}
+	return report
+}
+
+// NewDistribution summarises a set of values, percentiles are interpolated between the nearest values
+func NewDistribution(values []float64) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	var sum, squares float64
+	for _, v := range sorted {
+		sum += v
+	}
+	mean := sum / float64(len(sorted))
+	for _, v := range sorted {
+		squares += (v - mean) * (v - mean)
+	}
+	return Distribution{
+		Mean:   mean,
+		StdDev: math.Sqrt(squares / float64(len(sorted))),
+		Min:    sorted[0],
+		P5:     percentile(sorted, 5),
+		P25:    percentile(sorted, 25),
+		Median: percentile(sorted, 50),
+		P75:    percentile(sorted, 75),
+		P95:    percentile(sorted, 95),
+		Max:    sorted[len(sorted)-1],
+	}
+}
+
+// percentile finds the pth percentile of sorted values by linear interpolation
+func percentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// distributionHeader names the columns written for a Distribution in CSV reports
+var distributionHeader = []string{"mean", "stddev", "min", "p5", "p25", "median", "p75", "p95", "max"}
+
+// columns formats a Distribution as CSV columns, in the order of distributionHeader, rounded to 6 decimal places
+func (d Distribution) columns() []string {
+	values := []float64{d.Mean, d.StdDev, d.Min, d.P5, d.P25, d.Median, d.P75, d.P95, d.Max}
+	columns := make([]string, len(values))
+	for i, v := range values {
+		columns[i] = strconv.FormatFloat(math.Round(v*1e6)/1e6, 'f', -1, 64)
+	}
+	return columns
+}
+
+// WriteCSV writes the report as a table with a row for each measure, followed by a row for each city
+// The destruction rate of a city is its mean, as each game either destroys it or doesn't
+func (report *BatchReport) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	out.Write(append([]string{"measure", "city", "runs"}, distributionHeader...))
+	runs := strconv.Itoa(report.Runs)
+	measures := []struct {
+		name         string
+		distribution Distribution
+	}{
+		{"cities_destroyed", report.CitiesDestroyed},
+		{"surviving_monsters", report.Survivors},
+		{"trapped_monsters", report.Trapped},
+		{"steps", report.Steps},
+		{"largest_region", report.LargestRegion},
+	}
+	for _, m := range measures {
+		out.Write(append([]string{m.name, "", runs}, m.distribution.columns()...))
+	}
+	for _, city := range report.Cities {
+		values := make([]float64, report.Runs)
+		for i := 0; i < city.Destroyed; i++ {
+			values[i] = 1
+		}
+		out.Write(append([]string{"destroyed", string(city.City), runs}, NewDistribution(values).columns()...))
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// WriteJSON writes the report, including the result of every game, as JSON
+func (report *BatchReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// runBatch is the batch command, which plays many games on the same map and reports statistics about them
+func runBatch(args []string) error {
+	flags := flag.NewFlagSet("batch", flag.ExitOnError)
+	mapDataFn := flags.String("d", defaultMapDataFn, "input file path containing the map to play on")
+	inputFormat := flags.String("if", "", "format of the map data file, \"csv\" or \"json\" (default: guessed from the file extension)")
+	parseMode := flags.String("parse", "permissive", "how to treat problems in the map: \"permissive\", \"strict\" or \"lenient\"")
+	monsterCount := flags.Uint("n", 0, "number of monsters in each game (n > 0, default: the size of the spawn mix)")
+	runs := flags.Int("runs", 100, "number of games to play")
+	seed := flags.Int64("seed", 0, "seed from which the seed of each game is derived (default: time based)")
+	termination := flags.String("termination", "moves", terminationUsage)
+	strategy := flags.String("strategy", "uniform", strategyUsage)
+	combat := flags.String("combat", "annihilation", combatUsage)
+	stepMode := flags.String("step-mode", "sequential", stepModeUsage)
+	speciesFn := flags.String("species", "", speciesUsage)
+	mixSpec := flags.String("mix", "", mixUsage)
+	workers := flags.Int("workers", runtime.NumCPU(), "number of games to play at once")
+	outputFn := flags.String("o", "", "output file path to write the report to, writes to stdout as default")
+	outputFormat := flags.String("of", "", "format of the report, \"csv\" or \"json\" (default: guessed from the output file extension)")
+	flags.Parse(args)
+
+	mix, err := loadSpawnMix(*speciesFn, *mixSpec)
+	if err != nil {
+		return err
+	}
+	if *monsterCount == 0 {
+		*monsterCount = mix.Total()
+	}
+	if err := checkMonsterCount(*monsterCount, mix); err != nil {
+		return err
+	}
+	if *monsterCount == 0 || *runs < 1 {
+		flags.Usage()
+		return nil
+	}
+	config := BatchConfig{Runs: *runs, Monsters: *monsterCount, Seed: *seed, MaxIterations: 10000, Workers: *workers, Mix: mix}
+	if config.Termination, err = ParseTermination(*termination); err != nil {
+		return err
+	}
+	strategies, err := ParseStrategies(*strategy)
+	if err != nil {
+		return err
+	}
+	config.Strategies = strategies
+	if config.Combat, err = ParseCombatRule(*combat); err != nil {
+		return err
+	}
+	if config.StepMode, err = ParseStepMode(*stepMode); err != nil {
+		return err
+	}
+	format := *outputFormat
+	if format == "" {
+		format = FormatFromPath(*outputFn)
+	}
+	if format != "csv" && format != "json" {
+		return unknownFormatError("report", format, []string{"csv", "json"})
+	}
+
+	mode, err := ParseLoadMode(*parseMode)
+	if err != nil {
+		return err
+	}
+	world, err := loadWorld(*mapDataFn, *inputFormat, mode)
+	if err != nil {
+		return err
+	}
+	config.Seed = resolveSeed(flags, config.Seed)
+
+	report := PlayBatch(world, config)
+
+	var target io.Writer = os.Stdout
+	if *outputFn != "" {
+		file, err := os.Create(*outputFn)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		target = file
+	}
+	if format == "json" {
+		return report.WriteJSON(target)
+	}
+	return report.WriteCSV(target)
+}
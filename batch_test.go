@@ -0,0 +1,39 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewDistribution(t *testing.T) {
+	d := NewDistribution([]float64{4, 1, 3, 2, 5})
+	expected := Distribution{Mean: 3, StdDev: 1.4142135623730951, Min: 1, P5: 1.2, P25: 2, Median: 3, P75: 4, P95: 4.8, Max: 5}
+	if d != expected {
+		t.Errorf("Expected %+v, got %+v", expected, d)
+	}
+}
+
+func TestPlayBatchIsReproducible(t *testing.T) {
+	world, _ := BuildWorldFromRecords(NewCSVReader(strings.NewReader(serverTestMap)).ReadAll())
+	config := BatchConfig{Runs: 20, Monsters: 2, Seed: 1, MaxIterations: 100, Workers: 1}
+	serial := PlayBatch(world, config)
+	config.Workers = 4
+	parallel := PlayBatch(world, config)
+
+	if !reflect.DeepEqual(serial, parallel) {
+		t.Errorf("Expected the same report however many games are played at once, got\n%+v\n%+v", serial, parallel)
+	}
+	for _, city := range world.GetCities() {
+		if city.Destroyed || !city.Monsters.IsEmpty() {
+			t.Errorf("Expected the batch to leave %s untouched", city.Name)
+		}
+	}
+	destroyed := 0
+	for _, city := range serial.Cities {
+		destroyed += city.Destroyed
+	}
+	if float64(destroyed) != serial.CitiesDestroyed.Mean*float64(config.Runs) {
+		t.Errorf("Expected the city counts to add up to the cities destroyed, got %d and %+v", destroyed, serial.CitiesDestroyed)
+	}
+}
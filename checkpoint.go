@@ -0,0 +1,375 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+)
+
+// The sources of math/rand are additive lagged Fibonacci generators: once rngLen numbers have been drawn, each number
+// is the sum of the numbers drawn rngLen and rngTap draws before it
+const (
+	rngLen = 607
+	rngTap = 273
+)
+
+// countingSource is a seeded random source which counts the numbers drawn from it and remembers the latest of them
+// math/rand can't save the state of a source, so it is saved as the seed, the count and the last rngLen numbers drawn
+// instead, from which the numbers which follow can be worked out
+type countingSource struct {
+	seed   int64
+	draws  uint64
+	recent [rngLen]uint64 // The last rngLen numbers drawn, the oldest at recent[draws%rngLen]
+	source uint64Source
+}
+
+// uint64Source is a source of random numbers which a countingSource draws from
+type uint64Source interface {
+	Uint64() uint64
+}
+
+func newCountingSource(seed int64) *countingSource {
+	return &countingSource{seed: seed, source: rand.NewSource(seed).(rand.Source64)}
+}
+
+// Int63 draws a number, the standard sources give the same number as Uint64 without its top bit
+func (s *countingSource) Int63() int64 {
+	return int64(s.Uint64() & (1<<63 - 1))
+}
+
+// Uint64 draws a number
+func (s *countingSource) Uint64() uint64 {
+	x := s.source.Uint64()
+	s.recent[s.draws%rngLen] = x
+	s.draws++
+	return x
+}
+
+// Seed restarts the source from a new seed
+func (s *countingSource) Seed(seed int64) {
+	s.seed, s.draws = seed, 0
+	s.source = rand.NewSource(seed).(rand.Source64)
+}
+
+// skip draws numbers until draws have been drawn since the source was seeded
+func (s *countingSource) skip(draws uint64) {
+	for s.draws < draws {
+		s.Uint64()
+	}
+}
+
+// latest returns the last rngLen numbers drawn, oldest first, or nil if fewer have been drawn
+func (s *countingSource) latest() []uint64 {
+	if s.draws < rngLen {
+		return nil
+	}
+	latest := make([]uint64, rngLen)
+	for i := range latest {
+		latest[i] = s.recent[(s.draws+uint64(i))%rngLen]
+	}
+	return latest
+}
+
+// resumeCountingSource recreates a countingSource which has drawn draws numbers since it was seeded, the last of
+// which were latest, without drawing them all again
+func resumeCountingSource(seed int64, draws uint64, latest []uint64) *countingSource {
+	s := &countingSource{seed: seed, draws: draws}
+	lagged := &laggedSource{}
+	copy(lagged.vec[:], latest)
+	for i, x := range latest {
+		s.recent[(draws+uint64(i))%rngLen] = x
+	}
+	s.source = lagged
+	return s
+}
+
+// laggedSource carries on the numbers of a standard source from the last rngLen numbers it drew
+type laggedSource struct {
+	vec [rngLen]uint64 // The last rngLen numbers, the oldest at vec[pos]
+	pos int
+}
+
+// Uint64 draws a number
+func (s *laggedSource) Uint64() uint64 {
+	x := s.vec[s.pos] + s.vec[(s.pos+rngLen-rngTap)%rngLen]
+	s.vec[s.pos] = x
+	s.pos = (s.pos + 1) % rngLen
+	return x
+}
+
+// Checkpoint is the full state of a game between two steps, from which the game can be resumed
+// Observers are not part of a game's state, a resumed game only reports the events which happen after it was resumed
+type Checkpoint struct {
+	Seed          int64               `json:"seed"`
+	Draws         uint64              `json:"draws"`            // Numbers drawn from the random source since it was seeded
+	Latest        []uint64            `json:"latest,omitempty"` // The last 607 of them, oldest first, left out until that many have been drawn
+	Steps         int                 `json:"steps"`
+	Done          bool                `json:"done"`
+	MaxIterations int                 `json:"max_iterations"`
+	Termination   string              `json:"termination"`
+	StepMode      string              `json:"step_mode"`
+	Combat        string              `json:"combat"`
+	Species       []*Species          `json:"species,omitempty"`
+	Cities        []checkpointCity    `json:"cities"` // In map order
+	Monsters      []checkpointMonster `json:"monsters"`
+}
+
+// checkpointCity is the state of a city and its roads in a checkpoint
+type checkpointCity struct {
+	Name       CityName        `json:"name"`
+	Capacity   int             `json:"capacity"` // -1 for unlimited
+	Destroyed  bool            `json:"destroyed,omitempty"`
+	Attributes []CityAttribute `json:"attributes,omitempty"`
+	Roads      []Road          `json:"roads,omitempty"`
+	Monsters   []MonsterID     `json:"monsters,omitempty"` // Occupants, including the dead left in a destroyed city
+}
+
+// checkpointMonster is the state of a monster in a checkpoint
+type checkpointMonster struct {
+	ID       MonsterID `json:"id"`
+	Name     string    `json:"name"`
+	Location CityName  `json:"location"`
+	Moves    int       `json:"moves"`
+	Status   string    `json:"status"`
+	Strategy string    `json:"strategy"`
+	LastRoad *Road     `json:"last_road,omitempty"`
+	Strength int       `json:"strength,omitempty"`
+	Health   int       `json:"health,omitempty"`
+	Species  string    `json:"species,omitempty"`
+}
+
+var (
+	terminationNames = map[TerminationRule]string{EndAfterMonsterMoves: "moves", EndAfterIterations: "iterations"}
+	stepModeNames    = map[StepMode]string{SequentialSteps: "sequential", SimultaneousSteps: "simultaneous"}
+	statusNames      = map[string]MonsterStatus{}
+)
+
+func init() {
+	for _, status := range []MonsterStatus{StatusActive, StatusTrapped, StatusDead} {
+		statusNames[status.String()] = status
+	}
+}
+
+// Checkpoint captures the state of the game
+// Only games whose randomness comes from a seed, rather than WithRand, and which use built in strategies and combat rules
+// can be checkpointed
+func (g *MonsterGame) Checkpoint() (*Checkpoint, error) {
+	if g.source == nil {
+		return nil, fmt.Errorf("Only games played from a seed can be checkpointed")
+	}
+	cp := &Checkpoint{
+		Seed:          g.source.seed,
+		Draws:         g.source.draws,
+		Latest:        g.source.latest(),
+		Steps:         g.steps,
+		Done:          g.done,
+		MaxIterations: g.maxIterations,
+		Termination:   terminationNames[g.termination],
+		StepMode:      stepModeNames[g.stepMode],
+	}
+	var ok bool
+	if cp.Combat, ok = combatRuleName(g.combat); !ok {
+		return nil, fmt.Errorf("Combat rule %T can't be checkpointed", g.combat)
+	}
+
+	for _, city := range g.world.GetCities() {
+		c := checkpointCity{Name: city.Name, Capacity: city.Capacity(), Destroyed: city.Destroyed, Attributes: city.Attributes}
+		for _, road := range g.world.GetRoads(city.Name) {
+			c.Roads = append(c.Roads, *road)
+		}
+		for _, monster := range city.Monsters.GetAll() {
+			c.Monsters = append(c.Monsters, monster.ID)
+		}
+		cp.Cities = append(cp.Cities, c)
+	}
+
+	species := make(map[*Species]bool)
+	for _, monster := range g.Monsters.GetAll() {
+		m := checkpointMonster{
+			ID:       monster.ID,
+			Name:     monster.Name(),
+			Location: monster.Location(),
+			Moves:    monster.Moves(),
+			Status:   monster.Status().String(),
+			LastRoad: monster.LastRoad(),
+			Strength: monster.Strength(),
+			Health:   monster.Health(),
+		}
+		if m.Strategy, ok = strategyName(monster.Strategy()); !ok {
+			return nil, fmt.Errorf("Movement strategy %T can't be checkpointed", monster.Strategy())
+		}
+		if s := monster.Species(); s != nil {
+			m.Species = s.Name
+			if !species[s] {
+				species[s] = true
+				cp.Species = append(cp.Species, s)
+			}
+		}
+		cp.Monsters = append(cp.Monsters, m)
+	}
+	return cp, nil
+}
+
+// combatRuleName finds the name a built in combat rule is registered under
+func combatRuleName(rule CombatRule) (string, bool) {
+	for name, registered := range combatRules {
+		if registered == rule {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// strategyName finds the name a built in movement strategy is registered under
+func strategyName(strategy MovementStrategy) (string, bool) {
+	for name, registered := range movementStrategies {
+		if registered == strategy {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// ResumeGame rebuilds a game from a checkpoint, ready to play its next step
+// Options subscribe observers to the resumed game, options which change how it is played are overridden by the checkpoint
+func ResumeGame(cp *Checkpoint, opts ...GameOption) (*MonsterGame, error) {
+	game := &MonsterGame{
+		Monsters:       NewMonsterCollection(),
+		ActiveMonsters: NewMonsterCollection(),
+		world:          NewWorld(),
+	}
+	for _, opt := range opts {
+		opt(game)
+	}
+	game.steps, game.done, game.maxIterations = cp.Steps, cp.Done, cp.MaxIterations
+
+	var err error
+	if game.termination, err = ParseTermination(cp.Termination); err != nil {
+		return nil, err
+	}
+	if game.stepMode, err = ParseStepMode(cp.StepMode); err != nil {
+		return nil, err
+	}
+	if game.combat, err = ParseCombatRule(cp.Combat); err != nil {
+		return nil, err
+	}
+
+	species := &SpeciesConfig{Species: cp.Species}
+	for _, m := range cp.Monsters {
+		monster := NewMonsterWithName(uint(m.ID), m.Name)
+		monster.SetLocation(m.Location)
+		monster.moves = m.Moves
+		status, ok := statusNames[m.Status]
+		if !ok {
+			return nil, fmt.Errorf("Monster %s: unknown status %q", m.Name, m.Status)
+		}
+		monster.SetStatus(status)
+		strategies, err := ParseStrategies(m.Strategy)
+		if err != nil {
+			return nil, fmt.Errorf("Monster %s: %v", m.Name, err)
+		}
+		monster.SetStrategy(strategies[0])
+		monster.SetLastRoad(m.LastRoad)
+		if m.Species != "" {
+			if monster.species = species.Get(m.Species); monster.species == nil {
+				return nil, fmt.Errorf("Monster %s: unknown species %q", m.Name, m.Species)
+			}
+		}
+		monster.SetStrength(m.Strength)
+		monster.SetHealth(m.Health)
+		if err := game.Monsters.Add(monster); err != nil {
+			return nil, fmt.Errorf("Monster %s: %v", m.Name, err)
+		}
+		if status == StatusActive {
+			game.ActiveMonsters.Add(monster)
+		}
+	}
+
+	for _, c := range cp.Cities {
+		city := NewCity(c.Name, c.Capacity)
+		city.Destroyed = c.Destroyed
+		city.Attributes = c.Attributes
+		for _, id := range c.Monsters {
+			monster := game.Monsters.Get(id)
+			if monster == nil {
+				return nil, fmt.Errorf("City %s: unknown monster %d", c.Name, id)
+			}
+			city.Monsters.Add(monster)
+		}
+		if !game.world.AddCity(city) {
+			return nil, fmt.Errorf("City %s is defined more than once", c.Name)
+		}
+	}
+	for _, c := range cp.Cities {
+		for _, road := range c.Roads {
+			game.world.AddRoad(NewRoad(road.Direction, road.Source, road.Destination))
+		}
+	}
+
+	// The source carries on from the latest numbers it drew, only a checkpoint taken early on has to draw its numbers again
+	switch {
+	case len(cp.Latest) == rngLen:
+		game.source = resumeCountingSource(cp.Seed, cp.Draws, cp.Latest)
+	case len(cp.Latest) == 0:
+		game.source = newCountingSource(cp.Seed)
+		game.source.skip(cp.Draws)
+	default:
+		return nil, fmt.Errorf("Expected the latest %d random numbers, got %d", rngLen, len(cp.Latest))
+	}
+	game.rand = rand.New(game.source)
+	return game, nil
+}
+
+// WriteCheckpoint writes a checkpoint as JSON
+func WriteCheckpoint(w io.Writer, cp *Checkpoint) error {
+	return json.NewEncoder(w).Encode(cp)
+}
+
+// ReadCheckpoint reads a checkpoint written by WriteCheckpoint
+func ReadCheckpoint(r io.Reader) (*Checkpoint, error) {
+	var cp Checkpoint
+	if err := json.NewDecoder(r).Decode(&cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// saveCheckpoint writes the game's checkpoint to a file
+// It is written to a temporary file which then replaces the old checkpoint, so that a run killed while writing it
+// still leaves the previous checkpoint behind
+func saveCheckpoint(game *MonsterGame, path string) error {
+	cp, err := game.Checkpoint()
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if err := WriteCheckpoint(file, cp); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// loadCheckpoint reads a checkpoint file
+func loadCheckpoint(path string) (*Checkpoint, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	cp, err := ReadCheckpoint(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return cp, nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCountingSource(t *testing.T) {
+	plain := rand.New(rand.NewSource(3))
+	counting := newCountingSource(3)
+	r := rand.New(counting)
+	for i := 0; i < 100; i++ {
+		if a, b := plain.Intn(1000), r.Intn(1000); a != b {
+			t.Fatalf("Expected draw %d to match a plain source, got %d and %d", i, a, b)
+		}
+	}
+	resumed := newCountingSource(3)
+	resumed.skip(counting.draws)
+	if a, b := r.Int63(), rand.New(resumed).Int63(); a != b {
+		t.Errorf("Expected a skipped source to carry on where the other left off, got %d and %d", a, b)
+	}
+}
+
+func TestResumeCountingSource(t *testing.T) {
+	plain := rand.New(rand.NewSource(7))
+	counting := newCountingSource(7)
+	r := rand.New(counting)
+	for i := 0; i < 2000; i++ {
+		plain.Int63()
+		r.Int63()
+	}
+	resumed := rand.New(resumeCountingSource(7, counting.draws, counting.latest()))
+	for i := 0; i < 2000; i++ {
+		if a, b := plain.Uint64(), resumed.Uint64(); a != b {
+			t.Fatalf("Expected draw %d after resuming to match a plain source, got %d and %d", i, a, b)
+		}
+		if a, b := plain.Intn(1000), resumed.Intn(1000); a != b {
+			t.Fatalf("Expected draw %d after resuming to match a plain source, got %d and %d", i, a, b)
+		}
+	}
+	if newCountingSource(7).latest() != nil {
+		t.Error("Expected no latest numbers before enough have been drawn")
+	}
+}
+
+// The sources of math/rand must stay lagged Fibonacci generators for checkpoints to carry on from their latest numbers
+func TestStandardSourceIsLaggedFibonacci(t *testing.T) {
+	source := rand.NewSource(11).(rand.Source64)
+	drawn := make([]uint64, 3*rngLen)
+	for i := range drawn {
+		drawn[i] = source.Uint64()
+	}
+	for i := rngLen; i < len(drawn); i++ {
+		if drawn[i] != drawn[i-rngLen]+drawn[i-rngTap] {
+			t.Fatalf("rand.NewSource is no longer a lagged Fibonacci generator, checkpoints can't be resumed from their latest numbers")
+		}
+	}
+	other := rand.NewSource(11)
+	for i := range drawn {
+		if uint64(other.Int63()) != drawn[i]&(1<<63-1) {
+			t.Fatalf("rand.NewSource's Int63 no longer matches its Uint64, checkpoints can't be resumed from their latest numbers")
+		}
+	}
+}
+
+func TestCheckpointResume(t *testing.T) {
+	species, _ := ReadSpeciesConfig(strings.NewReader(speciesTestConfig))
+	configs := map[string][]GameOption{
+		"default":      nil,
+		"species":      {WithSpawnMix(species.Mix), WithCombat(HealthCombat{}), WithStrategies(MomentumStrategy{}, SeekStrategy{})},
+		"simultaneous": {WithStepMode(SimultaneousSteps), WithCombat(RandomWinnerCombat{}), WithTermination(EndAfterIterations)},
+	}
+	// A checkpoint taken early on has its random numbers drawn again, a later one carries on from the latest numbers
+	// The cities of the later games are unlimited so that the monsters live long enough to draw them
+	checkpoints := []struct {
+		name    string
+		late    bool
+		reached func(game *MonsterGame) bool
+	}{
+		{"early", false, func(game *MonsterGame) bool { return game.Steps() >= 3 }},
+		{"late", true, func(game *MonsterGame) bool {
+			cp, _ := game.Checkpoint()
+			return cp.Latest != nil
+		}},
+	}
+	for _, checkpoint := range checkpoints {
+		for config, opts := range configs {
+			name := checkpoint.name + " " + config
+			newGame := func() *MonsterGame {
+				file, _ := os.Open("assets/world_map_small.txt")
+				defer file.Close()
+				world, _ := BuildWorldFromRecords(NewCSVReader(file).ReadAll())
+				if checkpoint.late {
+					world.SetDefaultCapacity(-1)
+				}
+				return NewMonsterGame(world, 1000, 8, append([]GameOption{WithSeed(4)}, opts...)...)
+			}
+			testCheckpointResume(t, name, newGame, checkpoint.reached, checkpoint.late)
+		}
+	}
+}
+
+// testCheckpointResume checks that a game resumed from a checkpoint, taken once reached is true, plays out like the original
+// A late checkpoint is expected to hold the latest random numbers drawn
+func testCheckpointResume(t *testing.T, name string, newGame func() *MonsterGame, reached func(*MonsterGame) bool, late bool) {
+	// Play the whole game, recording the events after the checkpoint
+	game := newGame()
+	for !reached(game) && game.Step() {
+	}
+	var played bytes.Buffer
+	game.Subscribe(NewEventLogWriter(&played))
+	game.Start()
+	var playedWorld bytes.Buffer
+	NewCSVWriter(&playedWorld).WriteAll(GetLosslessWorldRecords(game.World()))
+
+	// Play it again, stopping at the checkpoint and resuming from a saved copy
+	game = newGame()
+	for !reached(game) && game.Step() {
+	}
+	cp, err := game.Checkpoint()
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if cp.Done {
+		t.Fatalf("%s: expected the game to still be running at the checkpoint", name)
+	}
+	if (cp.Latest != nil) != late {
+		t.Fatalf("%s: expected the checkpoint to hold the latest numbers only if late, got %d after %d draws", name, len(cp.Latest), cp.Draws)
+	}
+	var saved bytes.Buffer
+	WriteCheckpoint(&saved, cp)
+	if cp, err = ReadCheckpoint(&saved); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	var resumed bytes.Buffer
+	game, err = ResumeGame(cp, WithObserver(NewEventLogWriter(&resumed)))
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	game.Start()
+	var resumedWorld bytes.Buffer
+	NewCSVWriter(&resumedWorld).WriteAll(GetLosslessWorldRecords(game.World()))
+
+	if played.String() != resumed.String() {
+		t.Errorf("%s: expected the resumed game to play out like the original\noriginal:\n%s\nresumed:\n%s", name, played.String(), resumed.String())
+	}
+	if playedWorld.String() != resumedWorld.String() {
+		t.Errorf("%s: expected the resumed game to leave the same world\noriginal:\n%s\nresumed:\n%s", name, playedWorld.String(), resumedWorld.String())
+	}
+}
+
+func TestCheckpointNeedsSeed(t *testing.T) {
+	world, _ := BuildWorldFromRecords(NewCSVReader(strings.NewReader(serverTestMap)).ReadAll())
+	game := NewMonsterGame(world, 10, 1, WithRand(rand.New(rand.NewSource(1))))
+	if _, err := game.Checkpoint(); err == nil {
+		t.Error("Expected a game without a seed to refuse to checkpoint")
+	}
+}
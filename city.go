@@ -1,6 +1,10 @@
 package main
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
 
 // CityName is a string used to identify a city
 type CityName string
@@ -14,7 +18,57 @@ type City struct {
 	// Whether the city has been destroyed or not
 	Destroyed bool
 	// The number of monsters which causes the city to be destroyed and unreachable, -1 for never destroyed
+	// Monsters of heavy species count more than once towards it
 	maxMonsters int
+	// Optional properties given alongside the city's roads in the map data, kept so that they can be written back
+	Attributes []CityAttribute
+}
+
+// CityAttribute is an optional property of a city given alongside its roads in map data, e.g. capacity=3
+type CityAttribute struct {
+	Key   string
+	Value string
+}
+
+const (
+	// capacityAttribute sets the number of monsters which destroy a city, a positive number or "inf" for indestructible
+	capacityAttribute = "capacity"
+	// unlimitedCapacity is the capacity attribute value for cities which are never destroyed
+	unlimitedCapacity = "inf"
+	// defaultCityCapacity is the capacity of cities which don't have a capacity attribute, two monsters meeting destroy them
+	defaultCityCapacity = 2
+)
+
+// IsCityAttributeKey checks whether key names a city attribute rather than a road direction
+func IsCityAttributeKey(key string) bool {
+	return key == capacityAttribute
+}
+
+// ValidateCityAttribute checks that the value of an attribute can be used
+func ValidateCityAttribute(attr CityAttribute) error {
+	switch attr.Key {
+	case capacityAttribute:
+		_, err := ParseCapacity(attr.Value)
+		return err
+	}
+	return fmt.Errorf("unknown city attribute %q", attr.Key)
+}
+
+// ParseCapacity converts a capacity attribute value to a maximum number of monsters, -1 meaning unlimited
+func ParseCapacity(value string) (int, error) {
+	if value == unlimitedCapacity {
+		return -1, nil
+	}
+	capacity, err := strconv.Atoi(value)
+	if err != nil || capacity < 1 {
+		return 0, fmt.Errorf("capacity must be a positive whole number or %s", unlimitedCapacity)
+	}
+	return capacity, nil
+}
+
+// Capacity returns the number of monsters which cause the city to be destroyed, -1 if it is never destroyed
+func (c *City) Capacity() int {
+	return c.maxMonsters
 }
 
 var (
@@ -49,6 +103,37 @@ func (c *City) AddMonster(monster *Monster) (bool, error) {
 	return c.Destroyed, nil
 }
 
+// Enter adds a monster to the city's holdings, reporting whether the city is now full so that the monsters in it fight
+// Unlike AddMonster it leaves the outcome of the fight, and whether the city is destroyed, to the caller
+func (c *City) Enter(monster *Monster) (bool, error) {
+	if c.Destroyed {
+		return false, ErrCityDestroyed
+	}
+	if err := c.Monsters.Add(monster); err != nil {
+		return false, err
+	}
+	return c.Full(), nil
+}
+
+// Full checks whether the monsters in the city take up all of its capacity, so that they fight
+// A city is full once it holds as many monsters as its capacity, as with AddMonster
+// Weight only fills it earlier once there is more than one monster, so a heavy monster never fights alone
+func (c *City) Full() bool {
+	if c.maxMonsters == -1 {
+		return false
+	}
+	return c.Monsters.Length() >= c.maxMonsters || (c.Monsters.Length() > 1 && c.Load() >= c.maxMonsters)
+}
+
+// Load returns how much of the city's capacity its monsters take up, the sum of their weights
+func (c *City) Load() int {
+	load := 0
+	for _, monster := range c.Monsters.GetAll() {
+		load += monster.Weight()
+	}
+	return load
+}
+
 // RemoveMonster removes a monster from the city's holdings
 func (c *City) RemoveMonster(monster *Monster) {
 	c.Monsters.Remove(monster)
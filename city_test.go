@@ -27,6 +27,37 @@ func TestAddMonsterToCity(t *testing.T) {
 	}
 }
 
+func TestEnterCityWithWeights(t *testing.T) {
+	city := NewCity("a", 3)
+	giant := NewMonster(0)
+	giant.SetSpecies(&Species{Name: "giant", Health: 1, Speed: 1, Weight: 2})
+	if full, err := city.Enter(giant); full || err != nil {
+		t.Errorf("City should not be full under capacity")
+	}
+	if full, err := city.Enter(NewMonster(1)); !full || err != nil || city.Load() != 3 {
+		t.Errorf("City should be full once the weights of its monsters reach capacity, load is %d", city.Load())
+	}
+	if city.Destroyed {
+		t.Errorf("Entering a full city should leave the fight to the caller")
+	}
+}
+
+func TestLoneMonsterWeight(t *testing.T) {
+	city := NewCity("a", 2)
+	giant := NewMonster(0)
+	giant.SetSpecies(&Species{Name: "giant", Health: 1, Speed: 1, Weight: 2})
+	if full, err := city.Enter(giant); full || err != nil {
+		t.Errorf("A lone monster should not fill a city of capacity 2 however heavy it is")
+	}
+	if full, err := city.Enter(NewMonster(1)); !full || err != nil {
+		t.Errorf("City should be full once a second monster joins the giant")
+	}
+	// A city of capacity 1 is still filled by any single monster
+	if full, err := NewCity("b", 1).Enter(NewMonster(2)); !full || err != nil {
+		t.Errorf("A lone monster should fill a city of capacity 1")
+	}
+}
+
 func TestDestroyCity(t *testing.T) {
 	city := NewCity("asd", 2)
 	city.Destroy()
@@ -38,3 +69,17 @@ func TestDestroyCity(t *testing.T) {
 		t.Errorf("Should throw error when trying to destroy a destroyed city")
 	}
 }
+
+func TestParseCapacity(t *testing.T) {
+	if capacity, err := ParseCapacity("inf"); capacity != -1 || err != nil {
+		t.Errorf("Expected inf to be unlimited capacity")
+	}
+	if capacity, err := ParseCapacity("3"); capacity != 3 || err != nil {
+		t.Errorf("Expected a capacity of 3")
+	}
+	for _, value := range []string{"0", "-1", "two", ""} {
+		if _, err := ParseCapacity(value); err == nil {
+			t.Errorf("Expected capacity %q to be rejected", value)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// maxMonsterStrength is the strongest a monster can be under the strength rule, strengths are drawn from 1 to this
+const maxMonsterStrength = 10
+
+// CombatOutcome is the result of a fight in a city
+type CombatOutcome struct {
+	Survivors []*Monster // Monsters which live on in the city, ordered by id
+	Killed    []*Monster // Monsters which died, ordered by id
+	Destroyed bool       // Whether the city was destroyed
+}
+
+// CombatRule decides what happens when a city holds as many monsters as it can
+type CombatRule interface {
+	// Fight works out the outcome of a fight between the monsters in a full city, fighters are ordered by id
+	// city is nil for fights on roads, which destroy nothing whatever the outcome says
+	// All randomness must be drawn from r so that seeded games can be replayed
+	Fight(city *City, fighters []*Monster, r *rand.Rand) CombatOutcome
+}
+
+// monsterPreparer is implemented by combat rules which give each monster attributes as it is created
+type monsterPreparer interface {
+	Prepare(monster *Monster, r *rand.Rand)
+}
+
+// combatRules are the built in combat rules by the name used on the command line
+var combatRules = map[string]CombatRule{
+	"annihilation":  AnnihilationCombat{},
+	"random-winner": RandomWinnerCombat{},
+	"strength":      StrengthCombat{},
+	"health":        HealthCombat{},
+	"city-survives": CitySurvivesCombat{},
+}
+
+// ParseCombatRule converts the name of a combat rule, as used on the command line, to a CombatRule
+func ParseCombatRule(name string) (CombatRule, error) {
+	if rule, ok := combatRules[name]; ok {
+		return rule, nil
+	}
+	var names []string
+	for name := range combatRules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("Unknown combat rule %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+// AnnihilationCombat is the rule described in the rules of the game, every monster dies and the city is destroyed
+type AnnihilationCombat struct{}
+
+// Fight kills every monster and destroys the city
+func (AnnihilationCombat) Fight(city *City, fighters []*Monster, r *rand.Rand) CombatOutcome {
+	return CombatOutcome{Killed: fighters, Destroyed: true}
+}
+
+// RandomWinnerCombat picks one monster at random to win the fight and kill the others, the city is left standing
+type RandomWinnerCombat struct{}
+
+// Fight picks a winner at random
+func (RandomWinnerCombat) Fight(city *City, fighters []*Monster, r *rand.Rand) CombatOutcome {
+	return winnerTakesAll(fighters, r.Intn(len(fighters)))
+}
+
+// StrengthCombat lets the strongest monster win the fight and kill the others, leaving the city standing
+// If the strongest monsters are as strong as each other they kill each other and destroy the city, as in the rules
+type StrengthCombat struct{}
+
+// Prepare gives the monster a random strength, unless it has the attack of its species
+func (StrengthCombat) Prepare(monster *Monster, r *rand.Rand) {
+	if monster.Species() == nil {
+		monster.SetStrength(1 + r.Intn(maxMonsterStrength))
+	}
+}
+
+// Fight lets the strongest monster win, if there is only one of them
+func (StrengthCombat) Fight(city *City, fighters []*Monster, r *rand.Rand) CombatOutcome {
+	strongest := 0
+	tied := false
+	for i, monster := range fighters[1:] {
+		switch {
+		case monster.Strength() > fighters[strongest].Strength():
+			strongest, tied = i+1, false
+		case monster.Strength() == fighters[strongest].Strength():
+			tied = true
+		}
+	}
+	if tied {
+		return CombatOutcome{Killed: fighters, Destroyed: true}
+	}
+	return winnerTakesAll(fighters, strongest)
+}
+
+// HealthCombat has every monster strike each of the others at once, each taking the combined attack of the others as damage
+// Monsters whose health runs out die, the others live on with what is left of it. If every monster dies the city is
+// destroyed, as in the rules, otherwise it still stands
+type HealthCombat struct{}
+
+// Prepare gives monsters without a species a health of 1 and an attack of 1, so that they fight as in the rules
+func (HealthCombat) Prepare(monster *Monster, r *rand.Rand) {
+	if monster.Species() == nil {
+		monster.SetHealth(1)
+		monster.SetStrength(1)
+	}
+}
+
+// Fight deals each monster the damage of the others
+func (HealthCombat) Fight(city *City, fighters []*Monster, r *rand.Rand) CombatOutcome {
+	total := 0
+	for _, monster := range fighters {
+		total += monster.Strength()
+	}
+	var outcome CombatOutcome
+	for _, monster := range fighters {
+		monster.SetHealth(monster.Health() - (total - monster.Strength()))
+		if monster.Health() > 0 {
+			outcome.Survivors = append(outcome.Survivors, monster)
+		} else {
+			outcome.Killed = append(outcome.Killed, monster)
+		}
+	}
+	outcome.Destroyed = len(outcome.Survivors) == 0
+	return outcome
+}
+
+// CitySurvivesCombat kills every monster in the fight but leaves the city standing
+type CitySurvivesCombat struct{}
+
+// Fight kills every monster
+func (CitySurvivesCombat) Fight(city *City, fighters []*Monster, r *rand.Rand) CombatOutcome {
+	return CombatOutcome{Killed: fighters}
+}
+
+// winnerTakesAll is the outcome of a fight won by fighters[winner]
+func winnerTakesAll(fighters []*Monster, winner int) CombatOutcome {
+	outcome := CombatOutcome{Survivors: []*Monster{fighters[winner]}}
+	for i, monster := range fighters {
+		if i != winner {
+			outcome.Killed = append(outcome.Killed, monster)
+		}
+	}
+	return outcome
+}
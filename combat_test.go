@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func combatFighters(strengths ...int) []*Monster {
+	var fighters []*Monster
+	for i, strength := range strengths {
+		monster := NewMonsterWithName(uint(i), string(rune('A'+i))+"bc")
+		monster.SetStrength(strength)
+		fighters = append(fighters, monster)
+	}
+	return fighters
+}
+
+func TestCombatRules(t *testing.T) {
+	tests := []struct {
+		rule      CombatRule
+		strengths []int
+		survivors int
+		destroyed bool
+	}{
+		{AnnihilationCombat{}, []int{0, 0}, 0, true},
+		{RandomWinnerCombat{}, []int{0, 0, 0}, 1, false},
+		{StrengthCombat{}, []int{3, 7, 5}, 1, false},
+		{StrengthCombat{}, []int{7, 2, 7}, 0, true},
+		{CitySurvivesCombat{}, []int{0, 0}, 0, false},
+		{HealthCombat{}, []int{1, 1}, 0, true},
+	}
+	for _, test := range tests {
+		fighters := combatFighters(test.strengths...)
+		outcome := test.rule.Fight(NewCity("Bar", 2), fighters, rand.New(rand.NewSource(1)))
+		if len(outcome.Survivors) != test.survivors || outcome.Destroyed != test.destroyed {
+			t.Errorf("Expected %T to leave %d survivors and destroyed=%v with strengths %v, got %d and %v",
+				test.rule, test.survivors, test.destroyed, test.strengths, len(outcome.Survivors), outcome.Destroyed)
+		}
+		if len(outcome.Survivors)+len(outcome.Killed) != len(fighters) {
+			t.Errorf("Expected every fighter of %T to either survive or die, got %v", test.rule, outcome)
+		}
+	}
+
+	outcome := StrengthCombat{}.Fight(NewCity("Bar", 3), combatFighters(3, 7, 5), rand.New(rand.NewSource(1)))
+	if outcome.Survivors[0].Name() != "Bbc" {
+		t.Errorf("Expected the strongest monster to win, got %s", outcome.Survivors[0].Name())
+	}
+}
+
+func TestHealthCombat(t *testing.T) {
+	giant, imp := NewMonsterWithName(0, "Abc"), NewMonsterWithName(1, "Def")
+	giant.SetSpecies(&Species{Name: "giant", Health: 20, Attack: 6, Speed: 1, Weight: 2})
+	HealthCombat{}.Prepare(imp, nil)
+	outcome := HealthCombat{}.Fight(NewCity("Bar", 2), []*Monster{giant, imp}, nil)
+	if outcome.Destroyed || len(outcome.Survivors) != 1 || outcome.Survivors[0] != giant || giant.Health() != 19 {
+		t.Errorf("Expected the giant to kill the imp and lose 1 health, got %+v with %d health", outcome, giant.Health())
+	}
+}
+
+func TestParseCombatRule(t *testing.T) {
+	if rule, err := ParseCombatRule("strength"); err != nil || rule != (StrengthCombat{}) {
+		t.Errorf("Expected the strength rule, got %v %v", rule, err)
+	}
+	if _, err := ParseCombatRule("duel"); err == nil {
+		t.Error("Expected an unknown combat rule to be rejected")
+	}
+}
+
+func TestCombatRuleInGame(t *testing.T) {
+	loadSmallWorld := func() *World {
+		file, _ := os.Open("assets/world_map_small.txt")
+		defer file.Close()
+		world, _ := BuildWorldFromRecords(NewCSVReader(file).ReadAll())
+		return world
+	}
+
+	var played, eventLog bytes.Buffer
+	battles := 0
+	counter := GameObserverFunc(func(event GameEvent) {
+		switch event.(type) {
+		case BattleFought:
+			battles++
+		case CityDestroyed:
+			t.Error("Expected no city to be destroyed when cities survive fights")
+		}
+	})
+	world := loadSmallWorld()
+	game := NewMonsterGame(world, 100, 12, WithSeed(11), WithCombat(CitySurvivesCombat{}),
+		WithObserver(counter), WithObserver(NewTextObserver(&played)), WithObserver(NewEventLogWriter(&eventLog)))
+	game.Start()
+	if battles == 0 {
+		t.Fatal("Expected the monsters to fight")
+	}
+	for _, city := range world.GetCities() {
+		for _, monster := range city.Monsters.GetAll() {
+			if monster.Status() == StatusDead {
+				t.Errorf("Expected dead monsters to be cleared from %s, found %s", city.Name, monster.Name())
+			}
+		}
+	}
+	NewCSVWriter(&played).WriteAll(GetRemainingWorldRecords(world))
+
+	// The battles can be replayed from the event log
+	var replayed bytes.Buffer
+	world = loadSmallWorld()
+	if err := ReplayEventLog(NewReplay(world, NewTextObserver(&replayed)), NewEventLogReader(&eventLog), -1); err != nil {
+		t.Fatal(err)
+	}
+	NewCSVWriter(&replayed).WriteAll(GetRemainingWorldRecords(world))
+	if played.String() != replayed.String() {
+		t.Errorf("Expected replay output to match the game\ngame:\n%s\nreplay:\n%s", played.String(), replayed.String())
+	}
+}
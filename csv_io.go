@@ -11,6 +11,7 @@ import (
 type CSVReader struct{ reader io.Reader }
 
 // ReadAll reads CSV records one by one, converts them to a WorldRecord and sends them to an ouput channer
+// Malformed lines are sent as records holding a *ParseError, reading stops only if the underlying reader fails
 func (csvReader *CSVReader) ReadAll() (ch chan *WorldRecord) {
 	ch = make(chan *WorldRecord)
 	go func() {
@@ -26,28 +27,71 @@ func (csvReader *CSVReader) ReadAll() (ch chan *WorldRecord) {
 				if err == io.EOF {
 					break
 				}
-				panic(err)
-			}
-			cityName := CityName(rec[0])
-			record := &WorldRecord{City: cityName}
-
-			roads := make([]*Road, len(rec)-1)
-			for i, edge := range rec[1:] {
-				// Directions and their respective destinations are separated by '='
-				// e.g. North=Edinburgh
-				roadTuple := strings.Split(edge, "=")
-				direction := roadTuple[0]
-				destCityName := CityName(roadTuple[1])
-				roads[i] = NewRoad(direction, cityName, destCityName)
+				if csvErr, ok := err.(*csv.ParseError); ok {
+					ch <- &WorldRecord{
+						Line: csvErr.Line,
+						Err:  &ParseError{Line: csvErr.Line, Column: csvErr.Column, Reason: csvErr.Err.Error()},
+					}
+					continue
+				}
+				ch <- &WorldRecord{Err: err}
+				break
 			}
-			record.Roads = roads
-
-			ch <- record
+			ch <- parseCSVRecord(r, rec)
 		}
 	}()
 	return
 }
 
+// parseCSVRecord converts the fields of a CSV line to a WorldRecord, or to a record holding a *ParseError
+func parseCSVRecord(r *csv.Reader, rec []string) *WorldRecord {
+	line, _ := r.FieldPos(0)
+	// fieldError reports a problem with the field at index i
+	fieldError := func(i int, reason string) *WorldRecord {
+		line, column := r.FieldPos(i)
+		return &WorldRecord{Line: line, Err: &ParseError{Line: line, Column: column, Token: rec[i], Reason: reason}}
+	}
+
+	if rec[0] == "" {
+		return fieldError(0, "missing city name")
+	}
+	cityName := CityName(rec[0])
+	record := &WorldRecord{City: cityName, Line: line}
+
+	var roads []*Road
+	for i, edge := range rec[1:] {
+		if edge == "" {
+			return fieldError(i+1, "empty field, values must be separated by a single space")
+		}
+		// Directions and their respective destinations are separated by '='
+		// e.g. North=Edinburgh
+		roadTuple := strings.Split(edge, "=")
+		if len(roadTuple) != 2 {
+			return fieldError(i+1, "expected a road in the form direction=city")
+		}
+		direction := roadTuple[0]
+		destCityName := CityName(roadTuple[1])
+		if direction == "" {
+			return fieldError(i+1, "missing direction before '='")
+		}
+		if IsCityAttributeKey(direction) {
+			// Attributes share the key=value form with roads, e.g. capacity=3
+			attr := CityAttribute{Key: direction, Value: roadTuple[1]}
+			if err := ValidateCityAttribute(attr); err != nil {
+				return fieldError(i+1, err.Error())
+			}
+			record.Attributes = append(record.Attributes, attr)
+			continue
+		}
+		if destCityName == "" {
+			return fieldError(i+1, "missing destination city after '='")
+		}
+		roads = append(roads, NewRoad(direction, cityName, destCityName))
+	}
+	record.Roads = roads
+	return record
+}
+
 // NewCSVReader creates a new CSVReader which will read from the reader param
 func NewCSVReader(r io.Reader) *CSVReader {
 	return &CSVReader{reader: r}
@@ -63,12 +107,16 @@ func (csvWriter *CSVWriter) WriteAll(ch <-chan *WorldRecord) {
 	defer writer.Flush()
 	for {
 		if record, ok := <-ch; ok {
-			csvRecord := make([]string, len(record.Roads)+1)
+			csvRecord := make([]string, 0, len(record.Roads)+len(record.Attributes)+1)
 			// CityName is the first column
-			csvRecord[0] = string(record.City)
+			csvRecord = append(csvRecord, string(record.City))
 			// Following columns are the roads leading out of the city in the format "north=Edinburgh"
-			for i, road := range record.Roads {
-				csvRecord[i+1] = fmt.Sprintf("%s=%s", road.Direction, road.Destination)
+			for _, road := range record.Roads {
+				csvRecord = append(csvRecord, fmt.Sprintf("%s=%s", road.Direction, road.Destination))
+			}
+			// Attributes come last in the same format, e.g. "capacity=3"
+			for _, attr := range record.Attributes {
+				csvRecord = append(csvRecord, fmt.Sprintf("%s=%s", attr.Key, attr.Value))
 			}
 			writer.Write(csvRecord)
 		} else {
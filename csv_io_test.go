@@ -26,3 +26,77 @@ Esmosno north=Emexege south=Anegu east=Axesminilmo west=Dosmolixo
 		t.Errorf("Expected input and output csv to match")
 	}
 }
+
+func TestCSVReaderReportsParseErrors(t *testing.T) {
+	cases := []struct {
+		csvData string
+		line    int
+		column  int
+		token   string
+	}{
+		{"Foo north=Bar\nBar south=Foo west\n", 2, 15, "west"},
+		{"Foo north=Bar =Baz\n", 1, 15, "=Baz"},
+		{"Foo north=\n", 1, 5, "north="},
+		{"Foo north=Bar  south=Baz\n", 1, 15, ""},
+	}
+	for _, c := range cases {
+		_, err := BuildWorldFromRecords(NewCSVReader(strings.NewReader(c.csvData)).ReadAll())
+		parseErr, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("%q: expected a *ParseError, got %v", c.csvData, err)
+			continue
+		}
+		if parseErr.Line != c.line || parseErr.Column != c.column || parseErr.Token != c.token {
+			t.Errorf("%q: expected error at line %d, column %d on %q, got %v", c.csvData, c.line, c.column, c.token, parseErr)
+		}
+	}
+}
+
+func TestCSVReaderContinuesAfterParseErrors(t *testing.T) {
+	csvData := "Foo north\nBar south=Foo\n"
+	var records []*WorldRecord
+	for record := range NewCSVReader(strings.NewReader(csvData)).ReadAll() {
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	if records[0].Err == nil {
+		t.Errorf("Expected the first record to hold an error")
+	}
+	if records[1].Err != nil || records[1].City != "Bar" || records[1].Line != 2 {
+		t.Errorf("Expected the second line to be read as Bar, got %+v", records[1])
+	}
+}
+
+func TestCSVCityAttributes(t *testing.T) {
+	csvData := "Fortress north=Ruin capacity=inf\nRuin south=Fortress capacity=1\nTown west=Ruin\n"
+	world, err := BuildWorldFromRecords(NewCSVReader(strings.NewReader(csvData)).ReadAll())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[CityName]int{"Fortress": -1, "Ruin": 1, "Town": defaultCityCapacity}
+	for name, capacity := range expected {
+		if world.GetCity(name).Capacity() != capacity {
+			t.Errorf("Expected %s to have capacity %d, got %d", name, capacity, world.GetCity(name).Capacity())
+		}
+	}
+	if len(world.GetRoads("Fortress")) != 1 {
+		t.Errorf("Expected attributes not to be read as roads")
+	}
+
+	var b bytes.Buffer
+	NewCSVWriter(&b).WriteAll(GetLosslessWorldRecords(world))
+	if b.String() != csvData {
+		t.Errorf("Expected attributes to be written back\nexpected:\n%s\ngot:\n%s", csvData, b.String())
+	}
+}
+
+func TestCSVReaderRejectsBadCapacity(t *testing.T) {
+	for _, csvData := range []string{"Foo capacity=0", "Foo capacity=lots", "Foo north=Bar capacity="} {
+		_, err := BuildWorldFromRecords(NewCSVReader(strings.NewReader(csvData)).ReadAll())
+		if _, ok := err.(*ParseError); !ok {
+			t.Errorf("%q: expected a *ParseError, got %v", csvData, err)
+		}
+	}
+}
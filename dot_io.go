@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DOTWriter writes world records as a Graphviz DOT digraph, with roads as edges labelled by their direction
+// Given snapshot records, destroyed cities are greyed out and list the monsters which destroyed them, and cities holding monsters are highlighted
+type DOTWriter struct{ writer io.Writer }
+
+// WriteAll writes a node for each record, followed by the roads once the state of every city is known
+func (dotWriter *DOTWriter) WriteAll(ch <-chan *WorldRecord) {
+	writer := bufio.NewWriter(dotWriter.writer)
+	defer writer.Flush()
+
+	writer.WriteString("digraph world {\n")
+	writer.WriteString("  node [shape=box, style=filled, fillcolor=white];\n")
+
+	destroyed := make(map[CityName]bool)
+	var roads []*Road
+	for record := range ch {
+		label := string(record.City)
+		var attrs []string
+		switch {
+		case record.Destroyed:
+			destroyed[record.City] = true
+			if len(record.Monsters) > 0 {
+				label += "\ndestroyed by " + strings.Join(record.Monsters, ", ")
+			} else {
+				label += "\ndestroyed"
+			}
+			attrs = append(attrs, "fillcolor=lightgrey", "color=grey60", "fontcolor=grey45", `style="filled,dashed"`)
+		case len(record.Monsters) > 0:
+			label += "\n" + strings.Join(record.Monsters, ", ")
+			attrs = append(attrs, "fillcolor=gold", "penwidth=2")
+		}
+		attrs = append([]string{"label=" + dotQuote(label)}, attrs...)
+		fmt.Fprintf(writer, "  %s [%s];\n", dotQuote(string(record.City)), strings.Join(attrs, ", "))
+		roads = append(roads, record.Roads...)
+	}
+
+	for _, road := range roads {
+		attrs := "label=" + dotQuote(road.Direction)
+		if destroyed[road.Source] || destroyed[road.Destination] {
+			attrs += ", color=grey70, fontcolor=grey60, style=dashed"
+		}
+		fmt.Fprintf(writer, "  %s -> %s [%s];\n", dotQuote(string(road.Source)), dotQuote(string(road.Destination)), attrs)
+	}
+	writer.WriteString("}\n")
+}
+
+// dotQuote makes a DOT quoted string, lines are separated by "\n" escapes
+func dotQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	return `"` + s + `"`
+}
+
+// NewDOTWriter creates a new DOTWriter which will write WorldRecord records to DOT format
+func NewDOTWriter(w io.Writer) *DOTWriter {
+	return &DOTWriter{writer: w}
+}
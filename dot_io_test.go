@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDOTWriterDrawsWorldSnapshot(t *testing.T) {
+	world, _ := BuildWorldFromRecords(NewCSVReader(strings.NewReader("Foo north=Bar\nBar south=Foo east=Baz\nBaz west=Bar\n")).ReadAll())
+	bar, baz := world.GetCity("Bar"), world.GetCity("Baz")
+	bar.AddMonster(NewMonsterWithName(0, "Abc"))
+	bar.AddMonster(NewMonsterWithName(1, "Def"))
+	baz.AddMonster(NewMonsterWithName(2, "Ghi"))
+
+	var b bytes.Buffer
+	NewDOTWriter(&b).WriteAll(GetWorldSnapshotRecords(world))
+	dot := b.String()
+
+	expected := []string{
+		`"Foo" [label="Foo"];`,
+		`"Bar" [label="Bar\ndestroyed by Abc, Def", fillcolor=lightgrey`,
+		`"Baz" [label="Baz\nGhi", fillcolor=gold`,
+		`"Foo" -> "Bar" [label="north", color=grey70`,
+		`"Bar" -> "Baz" [label="east", color=grey70`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(dot, line) {
+			t.Errorf("Expected DOT output to contain %s\n%s", line, dot)
+		}
+	}
+	if !strings.HasPrefix(dot, "digraph world {") || !strings.HasSuffix(dot, "}\n") {
+		t.Errorf("Expected a complete digraph\n%s", dot)
+	}
+}
+
+func TestDOTQuote(t *testing.T) {
+	if quoted := dotQuote("Foo \"Bar\"\nBaz\\"); quoted != `"Foo \"Bar\"\nBaz\\"` {
+		t.Errorf("Unexpected quoting %s", quoted)
+	}
+}
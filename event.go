@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GameEvent is a notable change of state which happened during a MonsterGame
+type GameEvent interface {
+	// StepNumber returns the game step in which the event happened, step 0 is the initial placement of monsters
+	StepNumber() int
+}
+
+// MonsterRef identifies a monster taking part in an event
+type MonsterRef struct {
+	ID   MonsterID `json:"id"`
+	Name string    `json:"name"`
+}
+
+// refOf creates a MonsterRef for a monster
+func refOf(monster *Monster) MonsterRef {
+	return MonsterRef{ID: monster.ID, Name: monster.Name()}
+}
+
+// refsOf creates MonsterRefs for a list of monsters, keeping their order
+func refsOf(monsters []*Monster) []MonsterRef {
+	refs := make([]MonsterRef, len(monsters))
+	for i, monster := range monsters {
+		refs[i] = refOf(monster)
+	}
+	return refs
+}
+
+// MonsterSpawned happens when a monster is first placed in a city
+type MonsterSpawned struct {
+	Step    int
+	Monster MonsterRef
+	City    CityName
+}
+
+// MonsterMoved happens when a monster travels along a road from one city to another
+type MonsterMoved struct {
+	Step    int
+	Monster MonsterRef
+	From    CityName
+	To      CityName
+}
+
+// MonsterTrapped happens when a monster has no road left leading to an undestroyed city
+type MonsterTrapped struct {
+	Step    int
+	Monster MonsterRef
+	City    CityName
+}
+
+// CityDestroyed happens when monsters fight in a city, killing each other and destroying it
+type CityDestroyed struct {
+	Step     int
+	City     CityName
+	Monsters []MonsterRef // Monsters which fought and died, ordered by id
+}
+
+// BattleFought happens when monsters fight in a city without destroying it
+type BattleFought struct {
+	Step     int
+	City     CityName
+	Winners  []MonsterRef // Monsters which survived the fight, ordered by id
+	Monsters []MonsterRef // Monsters which died, ordered by id
+}
+
+// RoadBattleFought happens when monsters travelling in opposite directions along a road meet and fight on it
+// This only happens when monsters move simultaneously
+type RoadBattleFought struct {
+	Step     int
+	From     CityName // The road runs between From and To, in the direction the first of the monsters by id was travelling
+	To       CityName
+	Winners  []MonsterRef // Monsters which survived the fight and carried on, ordered by id
+	Monsters []MonsterRef // Monsters which died, ordered by id
+}
+
+// EndReason describes why a game finished
+type EndReason string
+
+const (
+	// EndNoActiveMonsters means every monster is either dead or trapped
+	EndNoActiveMonsters EndReason = "no active monsters left"
+	// EndAllCitiesDestroyed means there was nowhere left to place monsters
+	EndAllCitiesDestroyed EndReason = "all cities destroyed"
+	// EndMaxIterations means the game ran for its maximum number of steps
+	EndMaxIterations EndReason = "maximum iterations reached"
+	// EndAllMonstersMoved means every active monster has moved the required number of times
+	EndAllMonstersMoved EndReason = "every monster has moved enough"
+)
+
+// MonsterReport summarises the game of a monster which survived it
+type MonsterReport struct {
+	MonsterRef
+	City    CityName `json:"city"`
+	Moves   int      `json:"moves"`
+	Trapped bool     `json:"trapped,omitempty"`
+}
+
+// reportOf creates a MonsterReport for a monster
+func reportOf(monster *Monster) MonsterReport {
+	return MonsterReport{
+		MonsterRef: refOf(monster),
+		City:       monster.Location(),
+		Moves:      monster.Moves(),
+		Trapped:    monster.Status() == StatusTrapped,
+	}
+}
+
+// GameEnded happens once when the game finishes
+type GameEnded struct {
+	Step      int
+	Reason    EndReason
+	Survivors []MonsterReport // Monsters alive at the end of the game, including trapped ones, ordered by id
+}
+
+// StepNumber returns the step in which the monster was spawned
+func (e MonsterSpawned) StepNumber() int { return e.Step }
+
+// StepNumber returns the step in which the monster moved
+func (e MonsterMoved) StepNumber() int { return e.Step }
+
+// StepNumber returns the step in which the monster became trapped
+func (e MonsterTrapped) StepNumber() int { return e.Step }
+
+// StepNumber returns the step in which the city was destroyed
+func (e CityDestroyed) StepNumber() int { return e.Step }
+
+// StepNumber returns the step in which the battle was fought
+func (e BattleFought) StepNumber() int { return e.Step }
+
+// StepNumber returns the step in which the battle was fought
+func (e RoadBattleFought) StepNumber() int { return e.Step }
+
+// StepNumber returns the last step of the game
+func (e GameEnded) StepNumber() int { return e.Step }
+
+// GameObserver receives the events of a game as they happen
+type GameObserver interface {
+	OnEvent(event GameEvent)
+}
+
+// GameObserverFunc adapts a function to the GameObserver interface
+type GameObserverFunc func(event GameEvent)
+
+// OnEvent calls f(event)
+func (f GameObserverFunc) OnEvent(event GameEvent) {
+	f(event)
+}
+
+// TextObserver writes human readable destruction messages, e.g. "Bar has been destroyed by monster Abc and monster Def!"
+// followed by a report of the surviving monsters when the game ends
+type TextObserver struct{ writer io.Writer }
+
+// OnEvent writes a line for each destroyed city, each battle which left its city standing and each survivor, and ignores other events
+func (o *TextObserver) OnEvent(event GameEvent) {
+	switch e := event.(type) {
+	case CityDestroyed:
+		io.WriteString(o.writer, FormatCityDestroyed(e)+"\n")
+	case BattleFought:
+		io.WriteString(o.writer, FormatBattle(e)+"\n")
+	case RoadBattleFought:
+		io.WriteString(o.writer, FormatRoadBattle(e)+"\n")
+	case GameEnded:
+		if len(e.Survivors) == 0 {
+			return
+		}
+		io.WriteString(o.writer, "\n")
+		for _, survivor := range e.Survivors {
+			io.WriteString(o.writer, FormatSurvivor(survivor)+"\n")
+		}
+	}
+}
+
+// NewTextObserver creates a TextObserver which writes to w
+func NewTextObserver(w io.Writer) *TextObserver {
+	return &TextObserver{writer: w}
+}
+
+// FormatCityDestroyed pretty prints a destruction event
+// E.g. Bar has been destroyed by monster Abc, monster Def and monster Ghi!
+func FormatCityDestroyed(e CityDestroyed) string {
+	var msg bytes.Buffer
+	msg.WriteString(fmt.Sprintf("%s has been destroyed by ", e.City))
+	msg.WriteString(joinMonsterNames(e.Monsters))
+	msg.WriteString("!")
+	return msg.String()
+}
+
+// FormatBattle pretty prints a battle which left its city standing
+// E.g. Monster Abc beat monster Def in Bar!
+// or Monster Abc and monster Def killed each other in Bar, which still stands!
+func FormatBattle(e BattleFought) string {
+	return formatFight(e.Winners, e.Monsters, fmt.Sprintf("in %s", e.City), fmt.Sprintf("in %s, which still stands", e.City))
+}
+
+// FormatRoadBattle pretty prints a battle fought on a road
+// E.g. Monster Abc and monster Def killed each other on the road between Foo and Bar!
+func FormatRoadBattle(e RoadBattleFought) string {
+	where := fmt.Sprintf("on the road between %s and %s", e.From, e.To)
+	return formatFight(e.Winners, e.Monsters, where, where)
+}
+
+// formatFight describes the outcome of a fight, where is the place it was fought and aftermath is used instead when nobody won
+func formatFight(winners, killed []MonsterRef, where, aftermath string) string {
+	if len(winners) == 0 {
+		if len(killed) == 1 {
+			return fmt.Sprintf("Monster %s died %s!", killed[0].Name, aftermath)
+		}
+		return fmt.Sprintf("%s killed each other %s!", capitalise(joinMonsterNames(killed)), aftermath)
+	}
+	return fmt.Sprintf("%s beat %s %s!", capitalise(joinMonsterNames(winners)), joinMonsterNames(killed), where)
+}
+
+// joinMonsterNames lists monsters in a sentence, e.g. monster Abc, monster Def and monster Ghi
+func joinMonsterNames(monsters []MonsterRef) string {
+	var msg bytes.Buffer
+	for i, monster := range monsters {
+		if i > 0 {
+			if i == len(monsters)-1 {
+				msg.WriteString(" and ")
+			} else {
+				msg.WriteString(", ")
+			}
+		}
+		msg.WriteString(fmt.Sprintf("monster %s", monster.Name))
+	}
+	return msg.String()
+}
+
+// capitalise makes the first letter of a sentence a capital
+func capitalise(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// FormatSurvivor pretty prints the report of a surviving monster
+// E.g. Monster Abc survived in Foo after 10000 moves
+func FormatSurvivor(r MonsterReport) string {
+	state := "survived"
+	if r.Trapped {
+		state = "survived trapped"
+	}
+	moves := "moves"
+	if r.Moves == 1 {
+		moves = "move"
+	}
+	return fmt.Sprintf("Monster %s %s in %s after %d %s", r.Name, state, r.City, r.Moves, moves)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// maxEventLogLineLength is the longest line accepted when reading an event log
+const maxEventLogLineLength = 64 * 1024 * 1024
+
+// Event types used in event logs
+const (
+	eventTypeSpawned    = "spawned"
+	eventTypeMoved      = "moved"
+	eventTypeTrapped    = "trapped"
+	eventTypeDestroyed  = "destroyed"
+	eventTypeBattle     = "battle"
+	eventTypeRoadBattle = "road-battle"
+	eventTypeEnded      = "ended"
+)
+
+// eventRecord is the JSON representation of a GameEvent, written as one line of an event log
+type eventRecord struct {
+	Type      string          `json:"type"`
+	Step      int             `json:"step"`
+	Monster   *MonsterRef     `json:"monster,omitempty"`
+	City      CityName        `json:"city,omitempty"`
+	From      CityName        `json:"from,omitempty"`
+	To        CityName        `json:"to,omitempty"`
+	Monsters  []MonsterRef    `json:"monsters,omitempty"`
+	Winners   []MonsterRef    `json:"winners,omitempty"`
+	Reason    EndReason       `json:"reason,omitempty"`
+	Survivors []MonsterReport `json:"survivors,omitempty"`
+}
+
+// newEventRecord converts a GameEvent to its JSON representation
+func newEventRecord(event GameEvent) (*eventRecord, error) {
+	switch e := event.(type) {
+	case MonsterSpawned:
+		return &eventRecord{Type: eventTypeSpawned, Step: e.Step, Monster: &e.Monster, City: e.City}, nil
+	case MonsterMoved:
+		return &eventRecord{Type: eventTypeMoved, Step: e.Step, Monster: &e.Monster, From: e.From, To: e.To}, nil
+	case MonsterTrapped:
+		return &eventRecord{Type: eventTypeTrapped, Step: e.Step, Monster: &e.Monster, City: e.City}, nil
+	case CityDestroyed:
+		return &eventRecord{Type: eventTypeDestroyed, Step: e.Step, City: e.City, Monsters: e.Monsters}, nil
+	case BattleFought:
+		return &eventRecord{Type: eventTypeBattle, Step: e.Step, City: e.City, Winners: e.Winners, Monsters: e.Monsters}, nil
+	case RoadBattleFought:
+		return &eventRecord{Type: eventTypeRoadBattle, Step: e.Step, From: e.From, To: e.To, Winners: e.Winners, Monsters: e.Monsters}, nil
+	case GameEnded:
+		return &eventRecord{Type: eventTypeEnded, Step: e.Step, Reason: e.Reason, Survivors: e.Survivors}, nil
+	}
+	return nil, fmt.Errorf("Unknown event type %T", event)
+}
+
+// event converts the JSON representation back to a GameEvent
+func (rec *eventRecord) event() (GameEvent, error) {
+	switch rec.Type {
+	case eventTypeSpawned, eventTypeMoved, eventTypeTrapped:
+		if rec.Monster == nil {
+			return nil, fmt.Errorf("%s event is missing a monster", rec.Type)
+		}
+	}
+	switch rec.Type {
+	case eventTypeSpawned:
+		return MonsterSpawned{Step: rec.Step, Monster: *rec.Monster, City: rec.City}, nil
+	case eventTypeMoved:
+		return MonsterMoved{Step: rec.Step, Monster: *rec.Monster, From: rec.From, To: rec.To}, nil
+	case eventTypeTrapped:
+		return MonsterTrapped{Step: rec.Step, Monster: *rec.Monster, City: rec.City}, nil
+	case eventTypeDestroyed:
+		return CityDestroyed{Step: rec.Step, City: rec.City, Monsters: rec.Monsters}, nil
+	case eventTypeBattle:
+		return BattleFought{Step: rec.Step, City: rec.City, Winners: rec.Winners, Monsters: rec.Monsters}, nil
+	case eventTypeRoadBattle:
+		return RoadBattleFought{Step: rec.Step, From: rec.From, To: rec.To, Winners: rec.Winners, Monsters: rec.Monsters}, nil
+	case eventTypeEnded:
+		return GameEnded{Step: rec.Step, Reason: rec.Reason, Survivors: rec.Survivors}, nil
+	}
+	return nil, fmt.Errorf("Unknown event type %q", rec.Type)
+}
+
+// EventLogWriter is a GameObserver which writes every event as one JSON object per line (NDJSON)
+type EventLogWriter struct {
+	encoder *json.Encoder
+	err     error
+}
+
+// OnEvent writes the event as a line of JSON, after the first failure further events are dropped
+func (lw *EventLogWriter) OnEvent(event GameEvent) {
+	if lw.err != nil {
+		return
+	}
+	rec, err := newEventRecord(event)
+	if err != nil {
+		lw.err = err
+		return
+	}
+	lw.err = lw.encoder.Encode(rec)
+}
+
+// Err returns the first error encountered while writing the log
+func (lw *EventLogWriter) Err() error {
+	return lw.err
+}
+
+// NewEventLogWriter creates an EventLogWriter which writes to w
+func NewEventLogWriter(w io.Writer) *EventLogWriter {
+	return &EventLogWriter{encoder: json.NewEncoder(w)}
+}
+
+// EventLogReader reads GameEvents from an NDJSON event log
+type EventLogReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// Next returns the next event in the log, or io.EOF when there are none left
+func (lr *EventLogReader) Next() (GameEvent, error) {
+	for lr.scanner.Scan() {
+		lr.line++
+		if len(lr.scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec eventRecord
+		if err := json.Unmarshal(lr.scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("Event log line %d: %v", lr.line, err)
+		}
+		event, err := rec.event()
+		if err != nil {
+			return nil, fmt.Errorf("Event log line %d: %v", lr.line, err)
+		}
+		return event, nil
+	}
+	if err := lr.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// NewEventLogReader creates an EventLogReader which reads from r
+func NewEventLogReader(r io.Reader) *EventLogReader {
+	scanner := bufio.NewScanner(r)
+	// The end of game event lists every survivor, so lines can be far longer than the default limit
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventLogLineLength)
+	return &EventLogReader{scanner: scanner}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestEventLogRoundTrip(t *testing.T) {
+	events := []GameEvent{
+		MonsterSpawned{Step: 0, Monster: MonsterRef{0, "Abc"}, City: "Foo"},
+		MonsterMoved{Step: 1, Monster: MonsterRef{0, "Abc"}, From: "Foo", To: "Bar"},
+		MonsterTrapped{Step: 2, Monster: MonsterRef{1, "Def"}, City: "Baz"},
+		CityDestroyed{Step: 3, City: "Bar", Monsters: []MonsterRef{{0, "Abc"}, {2, "Ghi"}}},
+		GameEnded{Step: 4, Reason: EndNoActiveMonsters},
+	}
+
+	var b bytes.Buffer
+	lw := NewEventLogWriter(&b)
+	for _, e := range events {
+		lw.OnEvent(e)
+	}
+	if lw.Err() != nil {
+		t.Fatal(lw.Err())
+	}
+
+	lr := NewEventLogReader(&b)
+	for i, expected := range events {
+		e, err := lr.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(e, expected) {
+			t.Errorf("Event %d: expected %#v, got %#v", i, expected, e)
+		}
+	}
+	if _, err := lr.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF at the end of the log, got %v", err)
+	}
+}
+
+func TestReplayReproducesGame(t *testing.T) {
+	loadSmallWorld := func() *World {
+		file, _ := os.Open("assets/world_map_small.txt")
+		defer file.Close()
+		world, _ := BuildWorldFromRecords(NewCSVReader(file).ReadAll())
+		return world
+	}
+
+	// Play a game, recording its messages, final world and event log
+	var played, eventLog bytes.Buffer
+	world := loadSmallWorld()
+	game := NewMonsterGame(world, 100, 12, WithSeed(11), WithObserver(NewTextObserver(&played)), WithObserver(NewEventLogWriter(&eventLog)))
+	game.Start()
+	NewCSVWriter(&played).WriteAll(GetRemainingWorldRecords(world))
+
+	// Replay it on a fresh copy of the map
+	var replayed bytes.Buffer
+	world = loadSmallWorld()
+	if err := ReplayEventLog(NewReplay(world, NewTextObserver(&replayed)), NewEventLogReader(&eventLog), -1); err != nil {
+		t.Fatal(err)
+	}
+	NewCSVWriter(&replayed).WriteAll(GetRemainingWorldRecords(world))
+
+	if played.String() != replayed.String() {
+		t.Errorf("Expected replay output to match the game\ngame:\n%s\nreplay:\n%s", played.String(), replayed.String())
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import "testing"
+
+func TestFormatCityDestroyed(t *testing.T) {
+	cases := []struct {
+		monsters []MonsterRef
+		expected string
+	}{
+		{[]MonsterRef{{0, "Abc"}, {1, "Def"}}, "Bar has been destroyed by monster Abc and monster Def!"},
+		{[]MonsterRef{{0, "Abc"}, {1, "Def"}, {2, "Ghi"}}, "Bar has been destroyed by monster Abc, monster Def and monster Ghi!"},
+		{[]MonsterRef{{0, "Abc"}}, "Bar has been destroyed by monster Abc!"},
+	}
+	for _, c := range cases {
+		if msg := FormatCityDestroyed(CityDestroyed{City: "Bar", Monsters: c.monsters}); msg != c.expected {
+			t.Errorf("Expected %q, got %q", c.expected, msg)
+		}
+	}
+}
+
+func TestFormatSurvivor(t *testing.T) {
+	active := MonsterReport{MonsterRef: MonsterRef{0, "Abc"}, City: "Foo", Moves: 10000}
+	if msg := FormatSurvivor(active); msg != "Monster Abc survived in Foo after 10000 moves" {
+		t.Errorf("Unexpected survivor report %q", msg)
+	}
+	trapped := MonsterReport{MonsterRef: MonsterRef{1, "Def"}, City: "Bar", Moves: 1, Trapped: true}
+	if msg := FormatSurvivor(trapped); msg != "Monster Def survived trapped in Bar after 1 move" {
+		t.Errorf("Unexpected survivor report %q", msg)
+	}
+}
+
+func TestFormatBattle(t *testing.T) {
+	cases := []struct {
+		winners  []MonsterRef
+		killed   []MonsterRef
+		expected string
+	}{
+		{[]MonsterRef{{0, "Abc"}}, []MonsterRef{{1, "Def"}, {2, "Ghi"}}, "Monster Abc beat monster Def and monster Ghi in Bar!"},
+		{nil, []MonsterRef{{0, "Abc"}, {1, "Def"}}, "Monster Abc and monster Def killed each other in Bar, which still stands!"},
+		{nil, []MonsterRef{{0, "Abc"}}, "Monster Abc died in Bar, which still stands!"},
+	}
+	for _, c := range cases {
+		if msg := FormatBattle(BattleFought{City: "Bar", Winners: c.winners, Monsters: c.killed}); msg != c.expected {
+			t.Errorf("Expected %q, got %q", c.expected, msg)
+		}
+	}
+}
+
+func TestFormatRoadBattle(t *testing.T) {
+	e := RoadBattleFought{From: "Foo", To: "Bar", Monsters: []MonsterRef{{0, "Abc"}, {1, "Def"}}}
+	if msg := FormatRoadBattle(e); msg != "Monster Abc and monster Def killed each other on the road between Foo and Bar!" {
+		t.Errorf("Unexpected road battle report %q", msg)
+	}
+	e = RoadBattleFought{From: "Foo", To: "Bar", Winners: []MonsterRef{{0, "Abc"}}, Monsters: []MonsterRef{{1, "Def"}}}
+	if msg := FormatRoadBattle(e); msg != "Monster Abc beat monster Def on the road between Foo and Bar!" {
+		t.Errorf("Unexpected road battle report %q", msg)
+	}
+}
@@ -1,143 +1,369 @@
 package main
 
 import (
-	"bytes"
 	"fmt"
-	"io"
 	"math/rand"
 	"time"
 )
 
+// TerminationRule decides when a game has been played for long enough
+type TerminationRule int
+
+const (
+	// EndAfterMonsterMoves ends the game once every active monster has moved at least maxIterations times, as described in the rules
+	// Trapped monsters can't move so they are not waited for
+	EndAfterMonsterMoves TerminationRule = iota
+	// EndAfterIterations ends the game after maxIterations steps, however many times each monster has moved
+	EndAfterIterations
+)
+
+// ParseTermination converts the name of a TerminationRule, as used on the command line, to a TerminationRule
+func ParseTermination(name string) (TerminationRule, error) {
+	switch name {
+	case "moves":
+		return EndAfterMonsterMoves, nil
+	case "iterations":
+		return EndAfterIterations, nil
+	}
+	return EndAfterMonsterMoves, fmt.Errorf("Unknown termination rule %q, expected moves or iterations", name)
+}
+
 // MonsterGame represents the game state
 type MonsterGame struct {
 	world          *World             // World map to navigate
+	Monsters       *MonsterCollection // Every monster in the game, whatever its status
 	ActiveMonsters *MonsterCollection // Keep track of monsters which are not dead or trapped in a location
 	done           bool               // Is the game finished
-	maxIterations  int                // Maximum number of steps before the game finishes
-	logger         io.Writer          // Log for output
+	steps          int                // Number of steps played so far, 0 while monsters are being placed
+	maxIterations  int                // Maximum number of steps or moves per monster before the game finishes
+	termination    TerminationRule    // How maxIterations is applied
+	observers      []GameObserver     // Receivers of game events
+	strategies     []MovementStrategy // Strategies given to new monsters in turn, by id
+	combat         CombatRule         // What happens when a city fills up with monsters
+	mix            SpawnMix           // Species given to new monsters, by id
+	stepMode       StepMode           // Whether monsters move one at a time or all at once
 	rand           *rand.Rand         // Random number generator
+	source         *countingSource    // Source of rand when the game is seeded, so that its state can be checkpointed
+}
+
+// GameOption configures optional behaviour of a MonsterGame
+type GameOption func(*MonsterGame)
+
+// WithSeed makes the game draw all of its randomness from a source seeded with seed, so that runs can be reproduced
+func WithSeed(seed int64) GameOption {
+	return func(g *MonsterGame) {
+		g.source = newCountingSource(seed)
+		g.rand = rand.New(g.source)
+	}
+}
+
+// WithRand makes the game draw all of its randomness from the provided random number generator
+func WithRand(r *rand.Rand) GameOption {
+	return func(g *MonsterGame) {
+		g.rand, g.source = r, nil
+	}
+}
+
+// WithTermination selects the rule deciding when the game has been played for long enough
+func WithTermination(rule TerminationRule) GameOption {
+	return func(g *MonsterGame) {
+		g.termination = rule
+	}
+}
+
+// WithStrategies gives the monsters movement strategies in turn, by id, so that one strategy can be given to every monster
+// or several can be compared in the same game. Monsters pick their roads uniformly at random otherwise
+func WithStrategies(strategies ...MovementStrategy) GameOption {
+	return func(g *MonsterGame) {
+		g.strategies = strategies
+	}
+}
+
+// WithCombat selects what happens when a city fills up with monsters, by default they kill each other and destroy it
+func WithCombat(rule CombatRule) GameOption {
+	return func(g *MonsterGame) {
+		g.combat = rule
+	}
+}
+
+// WithSpawnMix gives new monsters species, the first monsters by id taking the first species of the mix
+// Monsters beyond the end of the mix have no species
+func WithSpawnMix(mix SpawnMix) GameOption {
+	return func(g *MonsterGame) {
+		g.mix = mix
+	}
+}
+
+// WithStepMode selects whether the monsters move one at a time, as by default, or all at once in each step
+func WithStepMode(mode StepMode) GameOption {
+	return func(g *MonsterGame) {
+		g.stepMode = mode
+	}
+}
+
+// WithObserver subscribes an observer to the game's events, including the placement of the initial monsters
+func WithObserver(o GameObserver) GameOption {
+	return func(g *MonsterGame) {
+		g.Subscribe(o)
+	}
+}
+
+// Subscribe adds an observer which receives every subsequent game event
+func (g *MonsterGame) Subscribe(o GameObserver) {
+	g.observers = append(g.observers, o)
+}
+
+// emit sends an event to every observer in the order they subscribed
+func (g *MonsterGame) emit(event GameEvent) {
+	for _, o := range g.observers {
+		o.OnEvent(event)
+	}
+}
+
+// finish marks the game as done and announces why
+func (g *MonsterGame) finish(reason EndReason) {
+	if g.done {
+		return
+	}
+	g.done = true
+	var survivors []MonsterReport
+	for _, monster := range g.Monsters.GetAll() {
+		if monster.Status() != StatusDead {
+			survivors = append(survivors, reportOf(monster))
+		}
+	}
+	g.emit(GameEnded{
+		Step:      g.steps,
+		Reason:    reason,
+		Survivors: survivors,
+	})
+}
+
+// allMonstersMoved checks whether every active monster has moved at least maxIterations times
+func (g *MonsterGame) allMonstersMoved() bool {
+	for _, monster := range g.ActiveMonsters.GetAll() {
+		if monster.Moves() < g.maxIterations {
+			return false
+		}
+	}
+	return true
 }
 
 // Start runs the game until completion
 func (g *MonsterGame) Start() {
-	for i := 0; i < g.maxIterations; i++ {
-		if g.done {
-			break
-		}
-		// @TODO: introduce concurrency with mutexes on stateful struct fields
+	// @TODO: introduce concurrency with mutexes on stateful struct fields
+	for g.Step() {
+	}
+}
+
+// Step runs one iteration of the game, handing control back to the caller between steps
+// It returns false once the game is finished, the step which finds the game is over doesn't move any monsters
+func (g *MonsterGame) Step() bool {
+	if !g.done {
 		g.step()
 	}
-	g.done = true
+	return !g.done
 }
 
-// MoveMonsterRandomly transports a monster from its previous location (if any) to another random location
-func (g *MonsterGame) MoveMonsterRandomly(monster *Monster) error {
-	var possibleDestinations []*City
+// Done checks whether the game is finished
+func (g *MonsterGame) Done() bool {
+	return g.done
+}
+
+// Steps returns the number of steps played so far
+func (g *MonsterGame) Steps() int {
+	return g.steps
+}
 
+// World returns the world map the game is played on
+func (g *MonsterGame) World() *World {
+	return g.world
+}
+
+// MoveMonsterRandomly transports a monster from its previous location (if any) to another location
+// Monsters are placed in random cities, and then move along the roads picked by their movement strategy
+func (g *MonsterGame) MoveMonsterRandomly(monster *Monster) error {
+	var destCity *City
 	if monster.Location() != "" {
-		destinations, err := g.world.FindPossibleDestinations(monster.Location())
-		if err != nil {
+		move, err := g.chooseMove(monster)
+		if err != nil || move == nil {
 			return err
 		}
-		if len(destinations) == 0 {
-			// Trapped monsters are not active
-			if err := g.ActiveMonsters.Remove(monster); err != nil {
-				return err
-			}
-			return nil
-		}
-		possibleDestinations = destinations
+		destCity = move.City
 	} else {
 		// possibleDestinations are all remaining cities if there is no previous location
-		possibleDestinations = g.world.GetUndestroyedCities()
+		possibleDestinations := g.world.GetUndestroyedCities()
 		if len(possibleDestinations) == 0 {
-			// The game is finished if all cities are destroyed
-			g.done = true
+			// The game is finished if all cities are destroyed, the unplaced monster never joins it
+			g.ActiveMonsters.Remove(monster)
+			g.Monsters.Remove(monster)
+			g.finish(EndAllCitiesDestroyed)
 			return nil
 		}
+		// Random destination city
+		destCity = possibleDestinations[g.rand.Intn(len(possibleDestinations))]
 	}
 
+	full, err := g.travel(monster, destCity)
+	if err != nil {
+		return err
+	}
+	if full {
+		g.fight(destCity)
+	}
+	return nil
+}
+
+// chooseMove picks the road a placed monster takes next using its movement strategy
+// A monster with nowhere to go is trapped, and no move is returned
+func (g *MonsterGame) chooseMove(monster *Monster) (*MoveOption, error) {
+	moves, err := g.world.FindPossibleMoves(monster.Location())
+	if err != nil {
+		return nil, err
+	}
+	if len(moves) == 0 {
+		// Trapped monsters are not active, but they are still alive
+		if err := g.ActiveMonsters.Remove(monster); err != nil {
+			return nil, err
+		}
+		monster.SetStatus(StatusTrapped)
+		g.emit(MonsterTrapped{Step: g.steps, Monster: refOf(monster), City: monster.Location()})
+		return nil, nil
+	}
+	// The strategy sees the monster where it is now
+	move := moves[monster.Strategy().Choose(monster, moves, g.world, g.rand)]
+	monster.SetLastRoad(move.Road)
+	return &move, nil
+}
+
+// travel takes a monster from its previous location (if any) to a city, reporting whether the city is now full
+// It is left to the caller to settle the fight in a full city
+func (g *MonsterGame) travel(monster *Monster, destCity *City) (bool, error) {
 	// Remove the monster from the source city (if one has been set)
-	if monster.Location() != "" {
-		g.world.GetCity(monster.Location()).
+	source := monster.Location()
+	if source != "" {
+		g.world.GetCity(source).
 			RemoveMonster(monster)
 	}
 
-	// Random index with which to choose the destination
-	i := g.rand.Intn(len(possibleDestinations))
-	// Random destination city
-	destCity := possibleDestinations[i]
-
 	// Try to add the monster to the destination destCity
-	destroyed, err := destCity.AddMonster(monster)
+	full, err := destCity.Enter(monster)
 
 	// Check if the destCity unexpectedly ran out of space
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	monster.SetLocation(destCity.Name)
 
-	if destroyed {
-		count := 0
-		var msg bytes.Buffer
-		msg.WriteString(fmt.Sprintf("%s has been destroyed by ", destCity.Name))
-		for _, deadMonster := range destCity.Monsters.GetAll() {
-			// Dead monsters are not active
-			g.ActiveMonsters.Remove(deadMonster)
-
-			// Pretty print the monsters list
-			// E.g. monster 0, monster 1 and monster 2 etc
-			if count < destCity.Monsters.Length()-1 {
-				msg.WriteString(fmt.Sprintf("monster %s", deadMonster.Name()))
-				if count != destCity.Monsters.Length()-2 {
-					msg.WriteString(", ")
-				}
-			} else {
-				msg.WriteString(fmt.Sprintf(" and monster %s!\n", deadMonster.Name()))
-			}
-			count++
-		}
-		// Write the message to the game logger
-		g.logger.Write(msg.Bytes())
+	if source == "" {
+		g.emit(MonsterSpawned{Step: g.steps, Monster: refOf(monster), City: destCity.Name})
+	} else {
+		monster.AddMove()
+		g.emit(MonsterMoved{Step: g.steps, Monster: refOf(monster), From: source, To: destCity.Name})
 	}
-	return nil
+	return full, nil
+}
+
+// fight settles a fight between the monsters in a full city using the game's combat rule
+func (g *MonsterGame) fight(city *City) {
+	outcome := g.combat.Fight(city, city.Monsters.GetAll(), g.rand)
+	for _, deadMonster := range outcome.Killed {
+		// Dead monsters are not active
+		g.ActiveMonsters.Remove(deadMonster)
+		deadMonster.SetStatus(StatusDead)
+	}
+	if outcome.Destroyed {
+		// The dead stay in the destroyed city
+		city.Destroy()
+		g.emit(CityDestroyed{Step: g.steps, City: city.Name, Monsters: refsOf(outcome.Killed)})
+		return
+	}
+	if len(outcome.Killed) == 0 {
+		return
+	}
+	// The dead are cleared from a city which still stands, making room for more monsters
+	for _, deadMonster := range outcome.Killed {
+		city.RemoveMonster(deadMonster)
+	}
+	g.emit(BattleFought{Step: g.steps, City: city.Name, Winners: refsOf(outcome.Survivors), Monsters: refsOf(outcome.Killed)})
 }
 
 // step: runs one iteration of the game
 func (g *MonsterGame) step() {
 	// Game is done when no active monsters are left
 	if g.ActiveMonsters.Length() == 0 {
-		g.done = true
+		g.finish(EndNoActiveMonsters)
+		return
+	}
+	switch g.termination {
+	case EndAfterIterations:
+		if g.steps >= g.maxIterations {
+			g.finish(EndMaxIterations)
+			return
+		}
+	case EndAfterMonsterMoves:
+		if g.allMonstersMoved() {
+			g.finish(EndAllMonstersMoved)
+			return
+		}
+	}
+	g.steps++
+	if g.stepMode == SimultaneousSteps {
+		g.stepSimultaneously()
 		return
 	}
+	// Monsters move in id order so that a seeded game always plays out the same way
 	for _, monster := range g.ActiveMonsters.GetAll() {
-		if err := g.MoveMonsterRandomly(monster); err != nil {
-			panic(err)
+		// Fast monsters travel along several roads, stopping if they are killed or trapped on the way
+		for i := 0; i < monster.Speed(); i++ {
+			// Skip monsters which were killed or trapped earlier in this step
+			if !g.ActiveMonsters.Contains(monster.ID) {
+				break
+			}
+			if err := g.MoveMonsterRandomly(monster); err != nil {
+				panic(err)
+			}
 		}
 	}
 }
 
 // NewMonsterGame sets up a monster game, adding a number of monsters to the provided world in randomly selected locations
-func NewMonsterGame(w *World, maxIterations int, initialMonsterCount uint, logger io.Writer) *MonsterGame {
-	seededRand := rand.New(
-		rand.NewSource(time.Now().UnixNano()))
-
+// The game's randomness is seeded from the clock unless a WithSeed or WithRand option is provided
+func NewMonsterGame(w *World, maxIterations int, initialMonsterCount uint, opts ...GameOption) *MonsterGame {
 	game := &MonsterGame{
+		Monsters:       NewMonsterCollection(),
 		ActiveMonsters: NewMonsterCollection(),
 		world:          w,
 		maxIterations:  maxIterations,
-		logger:         logger,
-		rand:           seededRand,
+	}
+	for _, opt := range opts {
+		opt(game)
+	}
+	if game.rand == nil {
+		WithSeed(time.Now().UnixNano())(game)
+	}
+	if game.combat == nil {
+		game.combat = AnnihilationCombat{}
 	}
 
 	for monsterID := uint(0); monsterID < initialMonsterCount; monsterID++ {
 		if game.done {
 			break
 		}
-		// Create a monster
-		m := NewMonster(monsterID)
-		// Add it to the active monsters
+		// Create a monster, naming it from the game's random source so that names are reproducible
+		m := NewMonsterWithName(monsterID, generateMonsterName(game.rand))
+		if len(game.strategies) > 0 {
+			m.SetStrategy(game.strategies[monsterID%uint(len(game.strategies))])
+		}
+		if species := game.mix.speciesOf(monsterID); species != nil {
+			m.SetSpecies(species)
+		}
+		if preparer, ok := game.combat.(monsterPreparer); ok {
+			preparer.Prepare(m, game.rand)
+		}
+		// Add it to the game's monsters
+		game.Monsters.Add(m)
 		game.ActiveMonsters.Add(m)
 		// Place it on the map at random
 		if err := game.MoveMonsterRandomly(m); err != nil {
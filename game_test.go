@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"bytes"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -17,8 +19,147 @@ func TestMoveMonster(t *testing.T) {
 
 	var b bytes.Buffer
 	output := bufio.NewWriter(&b)
-	game := NewMonsterGame(world, 100, 20, output)
+	game := NewMonsterGame(world, 100, 20, WithObserver(NewTextObserver(output)))
 	game.Start()
 	output.Flush()
-	t.Logf(b.String())
+	t.Log(b.String())
+}
+
+func TestSeededGameNamesMonstersReproducibly(t *testing.T) {
+	newGame := func() *MonsterGame {
+		file, _ := os.Open("assets/world_map_small.txt")
+		defer file.Close()
+		world, _ := BuildWorldFromRecords(NewCSVReader(file).ReadAll())
+		return NewMonsterGame(world, 100, 5, WithSeed(42))
+	}
+
+	first, second := newGame(), newGame()
+	for _, monster := range first.ActiveMonsters.GetAll() {
+		other := second.ActiveMonsters.Get(monster.ID)
+		if other == nil {
+			continue
+		}
+		if monster.Name() != other.Name() {
+			t.Errorf("Expected monster %d to be named %s in both games, got %s", monster.ID, monster.Name(), other.Name())
+		}
+	}
+}
+
+func TestSeededGameOutputIsByteIdentical(t *testing.T) {
+	play := func() string {
+		file, _ := os.Open("assets/world_map_medium.txt")
+		defer file.Close()
+		world, _ := BuildWorldFromRecords(NewCSVReader(file).ReadAll())
+
+		var b bytes.Buffer
+		game := NewMonsterGame(world, 1000, 500, WithSeed(1564742215), WithObserver(NewTextObserver(&b)))
+		game.Start()
+		b.WriteString("\n")
+		NewCSVWriter(&b).WriteAll(GetRemainingWorldRecords(world))
+		return b.String()
+	}
+
+	first := play()
+	for i := 0; i < 3; i++ {
+		if play() != first {
+			t.Fatalf("Expected games with the same seed to produce identical output")
+		}
+	}
+}
+
+func TestGameEmitsEventsToEveryObserver(t *testing.T) {
+	file, _ := os.Open("assets/world_map_small.txt")
+	defer file.Close()
+	world, _ := BuildWorldFromRecords(NewCSVReader(file).ReadAll())
+
+	var first, second []GameEvent
+	game := NewMonsterGame(world, 50, 10, WithSeed(3),
+		WithObserver(GameObserverFunc(func(e GameEvent) { first = append(first, e) })))
+	game.Subscribe(GameObserverFunc(func(e GameEvent) { second = append(second, e) }))
+	game.Start()
+
+	spawned := 0
+	for _, e := range first {
+		if _, ok := e.(MonsterSpawned); ok {
+			spawned++
+		}
+	}
+	if spawned != 10 {
+		t.Errorf("Expected 10 MonsterSpawned events, got %d", spawned)
+	}
+
+	if len(second) == 0 {
+		t.Fatalf("Expected an observer subscribed after setup to receive events")
+	}
+	if _, ok := second[len(second)-1].(GameEnded); !ok {
+		t.Errorf("Expected the last event to be GameEnded, got %T", second[len(second)-1])
+	}
+	if !reflect.DeepEqual(second, first[len(first)-len(second):]) {
+		t.Errorf("Expected observers to receive the same events in the same order")
+	}
+}
+
+func TestTerminationRules(t *testing.T) {
+	// Cities of unlimited capacity are never destroyed, so every monster survives to keep moving
+	const unlimitedMap = "Foo north=Bar east=Baz capacity=inf\nBar south=Foo capacity=inf\nBaz west=Foo capacity=inf\n"
+	play := func(rule TerminationRule) GameEnded {
+		world, _ := BuildWorldFromRecords(NewCSVReader(strings.NewReader(unlimitedMap)).ReadAll())
+
+		var ended GameEnded
+		game := NewMonsterGame(world, 30, 4, WithSeed(5), WithTermination(rule),
+			WithObserver(GameObserverFunc(func(e GameEvent) {
+				if end, ok := e.(GameEnded); ok {
+					ended = end
+				}
+			})))
+		game.Start()
+		return ended
+	}
+
+	byMoves := play(EndAfterMonsterMoves)
+	if byMoves.Reason != EndAllMonstersMoved || len(byMoves.Survivors) != 4 {
+		t.Fatalf("Expected all 4 monsters to survive until they have all moved, got %+v", byMoves)
+	}
+	for _, survivor := range byMoves.Survivors {
+		if !survivor.Trapped && survivor.Moves < 30 {
+			t.Errorf("Expected active survivor %s to have moved at least 30 times, got %d", survivor.Name, survivor.Moves)
+		}
+	}
+
+	byIterations := play(EndAfterIterations)
+	if byIterations.Step > 30 {
+		t.Errorf("Expected the game to end within 30 steps, got %d", byIterations.Step)
+	}
+	for _, survivor := range byIterations.Survivors {
+		if survivor.Moves > 30 {
+			t.Errorf("Expected survivor %s to have moved at most 30 times, got %d", survivor.Name, survivor.Moves)
+		}
+	}
+}
+
+func TestParseTermination(t *testing.T) {
+	if rule, err := ParseTermination("iterations"); rule != EndAfterIterations || err != nil {
+		t.Errorf("Expected the iterations rule, got %v %v", rule, err)
+	}
+	if _, err := ParseTermination("never"); err == nil {
+		t.Error("Expected an unknown termination rule to be rejected")
+	}
+}
+
+func TestTrappedMonstersAreReported(t *testing.T) {
+	// A single city with no roads traps every monster in it
+	world := NewWorld()
+	world.AddCity(NewCity("Foo", -1))
+
+	game := NewMonsterGame(world, 10, 3, WithSeed(1))
+	game.Start()
+
+	if game.ActiveMonsters.Length() != 0 {
+		t.Errorf("Expected trapped monsters to be inactive")
+	}
+	for _, monster := range game.Monsters.GetAll() {
+		if monster.Status() != StatusTrapped {
+			t.Errorf("Expected monster %s to be trapped, got %s", monster.Name(), monster.Status())
+		}
+	}
 }
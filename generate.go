@@ -0,0 +1,294 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+)
+
+// Topologies of generated maps
+const (
+	// TopologyGrid joins every city to its neighbours on a rectangular grid
+	TopologyGrid = "grid"
+	// TopologyTorus is a grid whose edges wrap around, so that every city has four roads
+	TopologyTorus = "torus"
+	// TopologyTree joins the cities of a grid with a random spanning tree, then adds a fraction of the other grid roads
+	TopologyTree = "tree"
+	// TopologySparse is a grid with a fraction of its cities missing, leaving holes in the map
+	TopologySparse = "sparse"
+)
+
+// defaultDensities are the densities used for topologies which have one, when none is given
+var defaultDensities = map[string]float64{
+	TopologyTree:   0.2,
+	TopologySparse: 0.8,
+}
+
+// GenerateConfig describes a map to generate
+type GenerateConfig struct {
+	Topology string
+	Width    int // Columns of the grid the cities are placed on
+	Height   int // Rows of the grid the cities are placed on
+	// For tree maps, the fraction of grid roads outside the spanning tree which are added
+	// For sparse maps, the fraction of grid cells which hold a city
+	Density float64
+	Seed    int64
+}
+
+// generatedGrid records which cells of the grid hold cities and which neighbouring cells are joined by roads
+// Cells are numbered row by row from the north west corner, a road joining two cells runs both ways
+type generatedGrid struct {
+	width, height int
+	wrap          bool   // Whether the grid wraps around at its edges, as for a torus
+	present       []bool // Whether each cell holds a city
+	east          []bool // Whether each cell is joined to the cell to its east
+	south         []bool // Whether each cell is joined to the cell to its south
+}
+
+func newGeneratedGrid(width, height int, wrap bool) *generatedGrid {
+	cells := width * height
+	g := &generatedGrid{width: width, height: height, wrap: wrap, present: make([]bool, cells), east: make([]bool, cells), south: make([]bool, cells)}
+	for i := range g.present {
+		g.present[i] = true
+	}
+	return g
+}
+
+// neighbour returns the cell next to a cell in a direction, or -1 at the edge of a grid which doesn't wrap
+func (g *generatedGrid) neighbour(cell int, dir string) int {
+	x, y := cell%g.width, cell/g.width
+	offset := directionOffsets[dir]
+	x, y = x+offset.X, y+offset.Y
+	if g.wrap {
+		x, y = (x+g.width)%g.width, (y+g.height)%g.height
+	}
+	if x < 0 || y < 0 || x >= g.width || y >= g.height {
+		return -1
+	}
+	return y*g.width + x
+}
+
+// joined checks whether a cell has a road in a direction
+func (g *generatedGrid) joined(cell int, dir string) bool {
+	switch dir {
+	case East:
+		return g.east[cell]
+	case South:
+		return g.south[cell]
+	}
+	opposite, _ := OppositeDirection(dir)
+	if n := g.neighbour(cell, dir); n >= 0 {
+		return g.joined(n, opposite)
+	}
+	return false
+}
+
+// join adds a road between a cell and its neighbour to the east or south, if both hold cities
+func (g *generatedGrid) join(cell int, dir string) {
+	n := g.neighbour(cell, dir)
+	if n < 0 || !g.present[cell] || !g.present[n] {
+		return
+	}
+	if dir == East {
+		g.east[cell] = true
+	} else {
+		g.south[cell] = true
+	}
+}
+
+// GenerateWorld builds a map in which every road has a reverse road in the opposite direction
+// Cities are added row by row from the north west corner, each with its roads in the order north, south, east, west
+func GenerateWorld(config GenerateConfig) (*World, error) {
+	if config.Width < 1 || config.Height < 1 {
+		return nil, fmt.Errorf("The map must be at least 1 city wide and high")
+	}
+	if config.Density < 0 || config.Density > 1 {
+		return nil, fmt.Errorf("Density must be between 0 and 1")
+	}
+	r := rand.New(rand.NewSource(config.Seed))
+
+	var grid *generatedGrid
+	switch config.Topology {
+	case TopologyGrid:
+		grid = newGeneratedGrid(config.Width, config.Height, false)
+		grid.joinAll()
+	case TopologyTorus:
+		// Narrower tori would have two roads between the same pair of cities
+		if config.Width < 3 || config.Height < 3 {
+			return nil, fmt.Errorf("A torus must be at least 3 cities wide and high")
+		}
+		grid = newGeneratedGrid(config.Width, config.Height, true)
+		grid.joinAll()
+	case TopologyTree:
+		grid = newGeneratedGrid(config.Width, config.Height, false)
+		grid.joinSpanningTree(r, config.Density)
+	case TopologySparse:
+		grid = newGeneratedGrid(config.Width, config.Height, false)
+		for i := range grid.present {
+			grid.present[i] = r.Float64() < config.Density
+		}
+		grid.joinAll()
+	default:
+		return nil, fmt.Errorf("Unknown topology %q, expected one of %s", config.Topology,
+			strings.Join([]string{TopologyGrid, TopologyTorus, TopologyTree, TopologySparse}, ", "))
+	}
+	return grid.world(r), nil
+}
+
+// joinAll joins every pair of neighbouring cities
+func (g *generatedGrid) joinAll() {
+	for cell := range g.present {
+		g.join(cell, East)
+		g.join(cell, South)
+	}
+}
+
+// joinSpanningTree joins the cells with a random spanning tree, then joins each other pair of neighbours with probability extra
+// The tree is found with Kruskal's algorithm over the grid's roads in a random order
+func (g *generatedGrid) joinSpanningTree(r *rand.Rand, extra float64) {
+	type candidate struct {
+		cell int
+		dir  string
+	}
+	var candidates []candidate
+	for cell := range g.present {
+		for _, dir := range []string{East, South} {
+			if g.neighbour(cell, dir) >= 0 {
+				candidates = append(candidates, candidate{cell, dir})
+			}
+		}
+	}
+	r.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+
+	parent := make([]int, len(g.present))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	var rest []candidate
+	for _, c := range candidates {
+		a, b := find(c.cell), find(g.neighbour(c.cell, c.dir))
+		if a == b {
+			rest = append(rest, c)
+			continue
+		}
+		parent[a] = b
+		g.join(c.cell, c.dir)
+	}
+	for _, c := range rest {
+		if r.Float64() < extra {
+			g.join(c.cell, c.dir)
+		}
+	}
+}
+
+// world names the cities of the grid and builds a World from them
+func (g *generatedGrid) world(r *rand.Rand) *World {
+	names := make([]CityName, len(g.present))
+	nameCities(r, g.present, names)
+
+	world := NewWorld()
+	for cell, present := range g.present {
+		if present {
+			world.AddCity(NewCity(names[cell], defaultCityCapacity))
+		}
+	}
+	for cell, present := range g.present {
+		if !present {
+			continue
+		}
+		for _, dir := range []string{North, South, East, West} {
+			if g.joined(cell, dir) {
+				world.AddRoad(NewRoad(dir, names[cell], names[g.neighbour(cell, dir)]))
+			}
+		}
+	}
+	return world
+}
+
+// nameCities gives each present cell a unique name made with GenerateIdentifierFrom
+// Names are made longer as maps grow, so that few names have to be drawn again
+func nameCities(r *rand.Rand, present []bool, names []CityName) {
+	length := 4
+	// Identifiers alternate between 5 vowels and 21 consonants, aim for far more possible names than cities
+	for possible := 5.0 * 21 * 5 * 21; possible < float64(len(present))*100; length++ {
+		if length%2 == 0 {
+			possible *= 5
+		} else {
+			possible *= 21
+		}
+	}
+	used := make(map[CityName]bool)
+	for cell, ok := range present {
+		if !ok {
+			continue
+		}
+		for {
+			name := CityName(strings.Title(GenerateIdentifierFrom(r, length)))
+			if !used[name] {
+				used[name] = true
+				names[cell] = name
+				break
+			}
+		}
+	}
+}
+
+// runGenerate is the generate command, which writes a randomly generated map
+func runGenerate(args []string) error {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	topology := flags.String("topology", TopologyGrid, "shape of the map: \"grid\", \"torus\", \"tree\" (a random spanning tree plus extra roads) or \"sparse\" (a grid with holes)")
+	width := flags.Int("width", 10, "number of cities from west to east")
+	height := flags.Int("height", 10, "number of cities from north to south")
+	density := flags.Float64("density", -1, "fraction of the roads outside the spanning tree added to tree maps (default 0.2), or of the cells holding cities in sparse maps (default 0.8)")
+	seed := flags.Int64("seed", 0, "seed for the random number generator (default: time based)")
+	outputFn := flags.String("o", "", "output file path to write the map to, writes to stdout as default")
+	outputFormat := flags.String("of", "", "format to write the map in, \"csv\", \"json\" or \"dot\" (default: guessed from the output file extension)")
+	flags.Parse(args)
+
+	config := GenerateConfig{Topology: *topology, Width: *width, Height: *height, Density: *density, Seed: *seed}
+	if config.Density < 0 {
+		config.Density = defaultDensities[config.Topology]
+	}
+	config.Seed = resolveSeed(flags, config.Seed)
+
+	format := *outputFormat
+	if format == "" {
+		format = FormatFromPath(*outputFn)
+	}
+	// Fail before spending time on a large map
+	if err := checkOutputFormat(format); err != nil {
+		return err
+	}
+	world, err := GenerateWorld(config)
+	if err != nil {
+		return err
+	}
+
+	var target io.Writer = os.Stdout
+	if *outputFn != "" {
+		file, err := os.Create(*outputFn)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		target = file
+	}
+	writer, err := NewWorldStateWriter(format, target)
+	if err != nil {
+		return err
+	}
+	// Cities left without roads by the holes of a sparse map are still written
+	writer.WriteAll(GetLosslessWorldRecords(world))
+	return nil
+}
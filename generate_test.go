@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+func TestGenerateWorld(t *testing.T) {
+	tests := []struct {
+		topology      string
+		density       float64
+		cities, roads int
+	}{
+		// A 5x4 grid has 4*4 roads across and 5*3 roads down, each in both directions
+		{TopologyGrid, 0, 20, 2 * (16 + 15)},
+		{TopologyTorus, 0, 20, 4 * 20},
+		// A spanning tree of 20 cities has 19 roads each way
+		{TopologyTree, 0, 20, 2 * 19},
+		{TopologyTree, 1, 20, 2 * (16 + 15)},
+	}
+	for _, test := range tests {
+		world, err := GenerateWorld(GenerateConfig{Topology: test.topology, Width: 5, Height: 4, Density: test.density, Seed: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		roads := 0
+		for _, city := range world.GetCities() {
+			roads += len(world.GetRoads(city.Name))
+		}
+		if len(world.GetCities()) != test.cities || roads != test.roads {
+			t.Errorf("%s %v: expected %d cities and %d roads, got %d and %d", test.topology, test.density, test.cities, test.roads, len(world.GetCities()), roads)
+		}
+		if problems := NewMapValidator().CheckReverseRoads(world); len(problems) != 0 {
+			t.Errorf("%s: expected every road to have a reverse road, got %v", test.topology, problems)
+		}
+		if test.topology == TopologyTree && world.LargestSurvivingRegion() != test.cities {
+			t.Errorf("Expected the tree to join every city")
+		}
+	}
+}
+
+func TestGenerateSparseWorld(t *testing.T) {
+	world, err := GenerateWorld(GenerateConfig{Topology: TopologySparse, Width: 20, Height: 20, Density: 0.5, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(world.GetCities()); n < 100 || n > 300 {
+		t.Errorf("Expected about half of the 400 cells to hold cities, got %d", n)
+	}
+	if problems := NewMapValidator().CheckReverseRoads(world); len(problems) != 0 {
+		t.Errorf("Expected every road to have a reverse road, got %v", problems)
+	}
+	layout := LayoutWorld(world)
+	if len(layout.Conflicts) != 0 {
+		t.Errorf("Expected the map to lay out on a grid, got %v", layout.Conflicts)
+	}
+}
+
+func TestGenerateWorldRejectsBadConfig(t *testing.T) {
+	for _, config := range []GenerateConfig{
+		{Topology: TopologyGrid, Width: 0, Height: 3},
+		{Topology: TopologyTorus, Width: 2, Height: 3},
+		{Topology: TopologySparse, Width: 3, Height: 3, Density: 2},
+		{Topology: "hexagon", Width: 3, Height: 3},
+	} {
+		if _, err := GenerateWorld(config); err == nil {
+			t.Errorf("Expected %+v to be rejected", config)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// GridStyle is the set of characters used to draw a world on a grid
+type GridStyle struct {
+	Horizontal string // Road between cities next to each other on a row
+	Vertical   string // Road between cities next to each other in a column
+	Destroyed  string // Fill for destroyed cities
+}
+
+var (
+	// ASCIIGrid draws grids with plain ASCII characters
+	ASCIIGrid = GridStyle{Horizontal: "-", Vertical: "|", Destroyed: "#"}
+	// UnicodeGrid draws grids with box drawing characters
+	UnicodeGrid = GridStyle{Horizontal: "─", Vertical: "│", Destroyed: "░"}
+)
+
+const (
+	// gridNameWidth is the number of characters of a city's name shown in its cell
+	gridNameWidth = 4
+	// gridCellWidth is the width of a cell, the name followed by the number of monsters in the city
+	gridCellWidth = gridNameWidth + 1
+)
+
+// RenderGrid draws the world as a grid using the positions of a layout
+// Each city shows the start of its name followed by the number of monsters in it ('+' for more than 9), destroyed cities are
+// filled in, and roads between neighbouring cities which are still standing are drawn as lines
+func RenderGrid(w *World, layout *Layout, style GridStyle) string {
+	cells := layout.Cities()
+	cityAt := func(x, y int) *City {
+		if cities, ok := cells[GridPosition{x, y}]; ok {
+			return w.GetCity(cities[0])
+		}
+		return nil
+	}
+	// connected checks whether there is a road either way between two standing cities
+	connected := func(a, b *City) bool {
+		if a == nil || b == nil || a.Destroyed || b.Destroyed {
+			return false
+		}
+		return hasRoad(w, a.Name, b.Name) || hasRoad(w, b.Name, a.Name)
+	}
+
+	var grid strings.Builder
+	for y := 0; y < layout.Height; y++ {
+		var row, below strings.Builder
+		for x := 0; x < layout.Width; x++ {
+			city := cityAt(x, y)
+			row.WriteString(renderCell(city, style))
+			if x < layout.Width-1 {
+				if connected(city, cityAt(x+1, y)) {
+					row.WriteString(style.Horizontal)
+				} else {
+					row.WriteString(" ")
+				}
+			}
+
+			if connected(city, cityAt(x, y+1)) {
+				below.WriteString(strings.Repeat(" ", gridCellWidth/2) + style.Vertical + strings.Repeat(" ", gridCellWidth-gridCellWidth/2))
+			} else {
+				below.WriteString(strings.Repeat(" ", gridCellWidth+1))
+			}
+		}
+		grid.WriteString(strings.TrimRight(row.String(), " ") + "\n")
+		if y < layout.Height-1 {
+			grid.WriteString(strings.TrimRight(below.String(), " ") + "\n")
+		}
+	}
+	return grid.String()
+}
+
+// renderCell draws a single grid cell
+func renderCell(city *City, style GridStyle) string {
+	if city == nil {
+		return strings.Repeat(" ", gridCellWidth)
+	}
+	if city.Destroyed {
+		return strings.Repeat(style.Destroyed, gridCellWidth)
+	}
+	name := string(city.Name)
+	if len(name) > gridNameWidth {
+		name = name[:gridNameWidth]
+	}
+	count := " "
+	switch n := city.Monsters.Length(); {
+	case n > 9:
+		count = "+"
+	case n > 0:
+		count = fmt.Sprint(n)
+	}
+	return fmt.Sprintf("%-*s%s", gridNameWidth, name, count)
+}
+
+// hasRoad checks whether a road leads from one city to another
+func hasRoad(w *World, src, dest CityName) bool {
+	for _, road := range w.GetRoads(src) {
+		if road.Destination == dest {
+			return true
+		}
+	}
+	return false
+}
+
+// writeGrid lays out the world and draws it to a file, reporting layout conflicts to stderr
+func writeGrid(w *World, gridFn string, style GridStyle) error {
+	layout := LayoutWorld(w)
+	reportLayoutConflicts(layout)
+
+	file, err := os.Create(gridFn)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.WriteString(RenderGrid(w, layout, style))
+	return err
+}
+
+// reportLayoutConflicts writes the conflicts found when laying out a world to stderr
+func reportLayoutConflicts(layout *Layout) {
+	for _, conflict := range layout.Conflicts {
+		fmt.Fprintf(os.Stderr, "layout conflict: %s\n", conflict)
+	}
+}
+
+// runLayout implements the layout command, drawing a map as a grid worked out from the directions of its roads
+func runLayout(args []string) error {
+	flags := flag.NewFlagSet("layout", flag.ExitOnError)
+	mapDataFn := flags.String("d", defaultMapDataFn, "input file path containing the map to draw")
+	inputFormat := flags.String("if", "", "format of the map data file, \"csv\" or \"json\" (default: guessed from the file extension)")
+	unicode := flags.Bool("unicode", false, "draw the grid with box drawing characters instead of ASCII")
+	flags.Parse(args)
+
+	worldOfX, err := loadWorld(*mapDataFn, *inputFormat, LoadPermissive)
+	if err != nil {
+		return err
+	}
+
+	layout := LayoutWorld(worldOfX)
+	reportLayoutConflicts(layout)
+
+	style := ASCIIGrid
+	if *unicode {
+		style = UnicodeGrid
+	}
+	fmt.Print(RenderGrid(worldOfX, layout, style))
+	fmt.Fprintf(os.Stderr, "%d cities on a %dx%d grid, %d layout conflicts\n", len(layout.Positions), layout.Width, layout.Height, len(layout.Conflicts))
+	return nil
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderGrid(t *testing.T) {
+	csvData := "Foo north=Bar east=Baz\nBar south=Foo east=Qux\nBaz west=Foo north=Qux\nQux west=Bar south=Baz\n"
+	world, _ := BuildWorldFromRecords(NewCSVReader(strings.NewReader(csvData)).ReadAll())
+	world.GetCity("Foo").AddMonster(NewMonsterWithName(0, "Abc"))
+	world.GetCity("Qux").Destroy()
+
+	expected := "Bar   #####\n  |\nFoo 1-Baz\n"
+	if grid := RenderGrid(world, LayoutWorld(world), ASCIIGrid); grid != expected {
+		t.Errorf("Expected grid\n%s\ngot\n%s", expected, grid)
+	}
+}
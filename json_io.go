@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// jsonWorld is the JSON representation of a world map
+// e.g. {"cities": [{"name": "Foo", "roads": [{"direction": "north", "destination": "Bar"}], "attributes": {"capacity": "3"}}]}
+type jsonWorld struct {
+	Cities []*jsonCity `json:"cities"`
+}
+
+// jsonCity is the JSON representation of a WorldRecord
+type jsonCity struct {
+	Name       CityName          `json:"name"`
+	Roads      []jsonRoad        `json:"roads"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// jsonRoad is the JSON representation of a road leading out of a city
+type jsonRoad struct {
+	Direction   string   `json:"direction"`
+	Destination CityName `json:"destination"`
+}
+
+// lineCounter records where lines start in the data read through it, so that offsets can be reported as lines and columns
+type lineCounter struct {
+	reader     io.Reader
+	offset     int64
+	lineStarts []int64 // Offsets at which the second and following lines start
+}
+
+func (lc *lineCounter) Read(p []byte) (int, error) {
+	n, err := lc.reader.Read(p)
+	for i, b := range p[:n] {
+		if b == '\n' {
+			lc.lineStarts = append(lc.lineStarts, lc.offset+int64(i)+1)
+		}
+	}
+	lc.offset += int64(n)
+	return n, err
+}
+
+// position converts an offset of the data read so far to a line and column, both starting at 1
+func (lc *lineCounter) position(offset int64) (line, column int) {
+	i := sort.Search(len(lc.lineStarts), func(i int) bool { return lc.lineStarts[i] > offset })
+	start := int64(0)
+	if i > 0 {
+		start = lc.lineStarts[i-1]
+	}
+	return i + 1, int(offset-start) + 1
+}
+
+// JSONReader reads world records from a JSON document in the jsonWorld format
+type JSONReader struct{ reader io.Reader }
+
+// ReadAll decodes cities one by one, converts them to a WorldRecord and sends them to an output channel
+// Cities with invalid fields are sent as records holding a *ParseError, reading stops at the first syntax error
+func (jsonReader *JSONReader) ReadAll() (ch chan *WorldRecord) {
+	ch = make(chan *WorldRecord)
+	go func() {
+		defer close(ch)
+		lc := &lineCounter{reader: bufio.NewReader(jsonReader.reader)}
+		decoder := json.NewDecoder(lc)
+
+		// fail reports a problem at an offset of the input
+		fail := func(offset int64, token, reason string) {
+			line, column := lc.position(offset)
+			ch <- &WorldRecord{Line: line, Err: &ParseError{Line: line, Column: column, Token: token, Reason: reason}}
+		}
+		// failDecoding reports an error returned when decoding the value starting at offset base
+		failDecoding := func(err error, base int64) {
+			switch e := err.(type) {
+			case *json.SyntaxError:
+				fail(base+e.Offset, "", e.Error())
+			case *json.UnmarshalTypeError:
+				fail(base+e.Offset, e.Value, fmt.Sprintf("expected %s for %s", e.Type, e.Field))
+			default:
+				if err == io.EOF || err == io.ErrUnexpectedEOF {
+					fail(lc.offset, "", "unexpected end of JSON input")
+					return
+				}
+				ch <- &WorldRecord{Err: err}
+			}
+		}
+		// expect reads the next token, checking that it is the delimiter or key wanted
+		expect := func(wanted interface{}) bool {
+			offset := decoder.InputOffset()
+			token, err := decoder.Token()
+			if err != nil {
+				failDecoding(err, 0)
+				return false
+			}
+			if token != wanted {
+				fail(offset, fmt.Sprint(token), fmt.Sprintf("expected %v", wanted))
+				return false
+			}
+			return true
+		}
+
+		if !expect(json.Delim('{')) || !expect("cities") || !expect(json.Delim('[')) {
+			return
+		}
+		for decoder.More() {
+			var raw json.RawMessage
+			if err := decoder.Decode(&raw); err != nil {
+				failDecoding(err, 0)
+				return
+			}
+			// The raw city ends at the decoder's offset, which locates where it starts
+			start := decoder.InputOffset() - int64(len(raw))
+			line, _ := lc.position(start)
+			var city jsonCity
+			if err := json.Unmarshal(raw, &city); err != nil {
+				failDecoding(err, start)
+				continue
+			}
+			record, problem := city.record(line)
+			if problem != "" {
+				ch <- &WorldRecord{Line: line, Err: &ParseError{Line: line, Column: 1, Token: string(city.Name), Reason: problem}}
+				continue
+			}
+			ch <- record
+		}
+		if !expect(json.Delim(']')) || !expect(json.Delim('}')) {
+			return
+		}
+	}()
+	return
+}
+
+// record converts a decoded city to a WorldRecord, or describes what is wrong with it
+func (c *jsonCity) record(line int) (*WorldRecord, string) {
+	if c.Name == "" {
+		return nil, "missing city name"
+	}
+	record := &WorldRecord{City: c.Name, Line: line}
+	for _, road := range c.Roads {
+		if road.Direction == "" {
+			return nil, "road is missing a direction"
+		}
+		if road.Destination == "" {
+			return nil, fmt.Sprintf("road %s is missing a destination", road.Direction)
+		}
+		record.Roads = append(record.Roads, NewRoad(road.Direction, c.Name, road.Destination))
+	}
+	// Attributes have no order in JSON, so sort them to keep records deterministic
+	keys := make([]string, 0, len(c.Attributes))
+	for key := range c.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		attr := CityAttribute{Key: key, Value: c.Attributes[key]}
+		if err := ValidateCityAttribute(attr); err != nil {
+			return nil, err.Error()
+		}
+		record.Attributes = append(record.Attributes, attr)
+	}
+	return record, ""
+}
+
+// NewJSONReader creates a new JSONReader which will read from the reader param
+func NewJSONReader(r io.Reader) *JSONReader {
+	return &JSONReader{reader: r}
+}
+
+// JSONWriter writes world records as a JSON document in the jsonWorld format, one city per line
+type JSONWriter struct{ writer io.Writer }
+
+// WriteAll converts WorldRecord records one by one from the input channel to JSON and writes them
+func (jsonWriter *JSONWriter) WriteAll(ch <-chan *WorldRecord) {
+	writer := bufio.NewWriter(jsonWriter.writer)
+	defer writer.Flush()
+
+	writer.WriteString("{\"cities\": [")
+	first := true
+	for record := range ch {
+		city := jsonCity{Name: record.City, Roads: []jsonRoad{}}
+		for _, road := range record.Roads {
+			city.Roads = append(city.Roads, jsonRoad{Direction: road.Direction, Destination: road.Destination})
+		}
+		if len(record.Attributes) > 0 {
+			city.Attributes = make(map[string]string)
+			for _, attr := range record.Attributes {
+				city.Attributes[attr.Key] = attr.Value
+			}
+		}
+		data, err := json.Marshal(city)
+		if err != nil {
+			// jsonCity only holds strings, so this can't happen
+			panic(err)
+		}
+		if !first {
+			writer.WriteString(",")
+		}
+		first = false
+		writer.WriteString("\n  ")
+		writer.Write(data)
+	}
+	writer.WriteString("\n]}\n")
+}
+
+// NewJSONWriter creates a new JSONWriter which will write WorldRecord records to JSON format
+func NewJSONWriter(w io.Writer) *JSONWriter {
+	return &JSONWriter{writer: w}
+}
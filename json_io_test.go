@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestJSONReaderAndWriter(t *testing.T) {
+	csvData := `Denalmo north=Agixo-A south=Amolusnisnu east=Elolesme west=Migina capacity=3
+Asnu north=Ago-Mo south=Emexisno east=Dinexe west=Amiximine
+Fortress capacity=inf
+`
+	// csv -> json
+	var jsonData bytes.Buffer
+	NewJSONWriter(&jsonData).WriteAll(NewCSVReader(strings.NewReader(csvData)).ReadAll())
+
+	// json -> csv
+	var b bytes.Buffer
+	NewCSVWriter(&b).WriteAll(NewJSONReader(&jsonData).ReadAll())
+
+	if b.String() != csvData {
+		t.Errorf("Expected csv converted to json and back to match\nexpected:\n%s\ngot:\n%s", csvData, b.String())
+	}
+}
+
+func TestJSONReaderBuildsProvidedMap(t *testing.T) {
+	file, _ := os.Open("assets/world_map_medium.txt")
+	defer file.Close()
+	var jsonData bytes.Buffer
+	NewJSONWriter(&jsonData).WriteAll(NewCSVReader(file).ReadAll())
+
+	world, err := BuildWorldFromRecords(NewJSONReader(&jsonData).ReadAll())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(world.GetCities()) < 6763 {
+		t.Errorf("Expected every city of the medium map, got %d", len(world.GetCities()))
+	}
+}
+
+func TestJSONReaderReportsParseErrors(t *testing.T) {
+	cases := []struct {
+		jsonData string
+		line     int
+		token    string
+	}{
+		{"{\"cities\": [\n  {\"name\": \"Foo\", \"roads\": []},\n  {\"name\": \"Bar\", \"roads\": [{\"direction\": \"north\"}]}\n]}", 3, "Bar"},
+		{"{\"cities\": [\n  {\"name\": \"Foo\", \"attributes\": {\"capacity\": \"0\"}}\n]}", 2, "Foo"},
+		{"{\"cities\": [\n  {\"name\": \"Foo\", \"roads\": 7}\n]}", 2, "number"},
+		{"{\"towns\": []}", 1, "towns"},
+		{"{\"cities\": [\n  {\"name\": \"Foo\",,}\n]}", 2, ""},
+	}
+	for _, c := range cases {
+		_, err := BuildWorldFromRecords(NewJSONReader(strings.NewReader(c.jsonData)).ReadAll())
+		parseErr, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("%q: expected a *ParseError, got %v", c.jsonData, err)
+			continue
+		}
+		if parseErr.Line != c.line || parseErr.Token != c.token {
+			t.Errorf("%q: expected error on line %d with token %q, got %v", c.jsonData, c.line, c.token, parseErr)
+		}
+	}
+}
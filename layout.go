@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// GridPosition is a place on a grid, x grows to the east and y grows to the south
+type GridPosition struct {
+	X, Y int
+}
+
+// directionOffsets is how far a road in each compass direction moves on the grid
+var directionOffsets = map[string]GridPosition{
+	North: {0, -1},
+	South: {0, 1},
+	East:  {1, 0},
+	West:  {-1, 0},
+}
+
+// LayoutConflict is a road whose direction can't be embedded consistently in the grid
+type LayoutConflict struct {
+	Road    *Road
+	Message string
+}
+
+func (c LayoutConflict) String() string {
+	return fmt.Sprintf("%s %s=%s: %s", c.Road.Source, c.Road.Direction, c.Road.Destination, c.Message)
+}
+
+// Layout assigns every city of a world a position on a grid from the compass directions of its roads
+type Layout struct {
+	Positions  map[CityName]GridPosition
+	Conflicts  []LayoutConflict // Roads which disagree with the positions, in the order they were found
+	Width      int              // Number of columns used, positions start at 0
+	Height     int              // Number of rows used, positions start at 0
+	components int              // Number of disconnected parts of the world, laid out side by side
+}
+
+// componentGap is the number of empty columns between disconnected parts of the world
+const componentGap = 1
+
+// LayoutWorld works out grid positions for the cities of a world
+// Each road, in either direction, puts its destination one step from its source in the road's direction. Roads which contradict the
+// positions found so far, or put two cities in the same place, are reported as conflicts. Disconnected parts of the world are laid
+// out side by side, from west to east in the order of their first city.
+func LayoutWorld(w *World) *Layout {
+	layout := &Layout{Positions: make(map[CityName]GridPosition)}
+
+	// Roads are followed both ways, so find the roads leading into each city
+	incoming := make(map[CityName][]*Road)
+	for _, city := range w.GetCities() {
+		for _, road := range w.GetRoads(city.Name) {
+			incoming[road.Destination] = append(incoming[road.Destination], road)
+		}
+	}
+
+	for _, city := range w.GetCities() {
+		if _, ok := layout.Positions[city.Name]; ok {
+			continue
+		}
+		layout.addComponent(w, city.Name, incoming)
+	}
+	return layout
+}
+
+// addComponent lays out the cities connected to start, placing them to the east of the parts already laid out
+func (l *Layout) addComponent(w *World, start CityName, incoming map[CityName][]*Road) {
+	positions := map[CityName]GridPosition{start: {}}
+	occupants := map[GridPosition]CityName{{}: start}
+	queue := []CityName{start}
+	reported := make(map[*Road]bool)
+
+	// place puts a city at a position relative to start, or reports a conflict if the road disagrees with where it already is
+	place := func(road *Road, city CityName, pos GridPosition) {
+		if reported[road] {
+			return
+		}
+		if existing, ok := positions[city]; ok {
+			if existing != pos {
+				reported[road] = true
+				l.Conflicts = append(l.Conflicts, LayoutConflict{Road: road, Message: fmt.Sprintf("puts %s %s of where the other roads put it", city, describeOffset(existing, pos))})
+			}
+			return
+		}
+		if other, ok := occupants[pos]; ok {
+			reported[road] = true
+			l.Conflicts = append(l.Conflicts, LayoutConflict{Road: road, Message: fmt.Sprintf("puts %s in the same place as %s", city, other)})
+		} else {
+			occupants[pos] = city
+		}
+		positions[city] = pos
+		queue = append(queue, city)
+	}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		here := positions[current]
+		for _, road := range w.GetRoads(current) {
+			offset, ok := directionOffsets[road.Direction]
+			if !ok {
+				if !reported[road] {
+					reported[road] = true
+					l.Conflicts = append(l.Conflicts, LayoutConflict{Road: road, Message: "isn't a compass direction"})
+				}
+				continue
+			}
+			place(road, road.Destination, GridPosition{here.X + offset.X, here.Y + offset.Y})
+		}
+		for _, road := range incoming[current] {
+			offset, ok := directionOffsets[road.Direction]
+			if !ok {
+				continue
+			}
+			place(road, road.Source, GridPosition{here.X - offset.X, here.Y - offset.Y})
+		}
+	}
+
+	// Move the component so that it starts at the top, to the east of the previous components
+	minX, minY, maxX, maxY := 0, 0, 0, 0
+	for _, pos := range positions {
+		minX, minY = minInt(minX, pos.X), minInt(minY, pos.Y)
+		maxX, maxY = maxInt(maxX, pos.X), maxInt(maxY, pos.Y)
+	}
+	left := l.Width
+	if l.components > 0 {
+		left += componentGap
+	}
+	for city, pos := range positions {
+		l.Positions[city] = GridPosition{pos.X - minX + left, pos.Y - minY}
+	}
+	l.Width = left + maxX - minX + 1
+	l.Height = maxInt(l.Height, maxY-minY+1)
+	l.components++
+}
+
+// Cities returns the names of the cities at each position, sorted by row then column
+func (l *Layout) Cities() map[GridPosition][]CityName {
+	cells := make(map[GridPosition][]CityName)
+	for city, pos := range l.Positions {
+		cells[pos] = append(cells[pos], city)
+	}
+	for _, cities := range cells {
+		sort.Slice(cities, func(i, j int) bool { return cities[i] < cities[j] })
+	}
+	return cells
+}
+
+// describeOffset describes where one position is compared to another, e.g. "2 east and 1 north"
+func describeOffset(expected, actual GridPosition) string {
+	dx, dy := actual.X-expected.X, actual.Y-expected.Y
+	var parts []string
+	if dy < 0 {
+		parts = append(parts, fmt.Sprintf("%d %s", -dy, North))
+	} else if dy > 0 {
+		parts = append(parts, fmt.Sprintf("%d %s", dy, South))
+	}
+	if dx > 0 {
+		parts = append(parts, fmt.Sprintf("%d %s", dx, East))
+	} else if dx < 0 {
+		parts = append(parts, fmt.Sprintf("%d %s", -dx, West))
+	}
+	if len(parts) == 2 {
+		return parts[0] + " and " + parts[1]
+	}
+	return parts[0]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLayoutWorld(t *testing.T) {
+	csvData := "Foo north=Bar east=Baz\nBar south=Foo\nBaz west=Foo\nQux east=Zap\nZap west=Qux\n"
+	world, _ := BuildWorldFromRecords(NewCSVReader(strings.NewReader(csvData)).ReadAll())
+	layout := LayoutWorld(world)
+
+	if len(layout.Conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %v", layout.Conflicts)
+	}
+	expected := map[CityName]GridPosition{
+		"Bar": {0, 0},
+		"Foo": {0, 1},
+		"Baz": {1, 1},
+		// The second component starts after a gap to the east
+		"Qux": {3, 0},
+		"Zap": {4, 0},
+	}
+	for city, pos := range expected {
+		if layout.Positions[city] != pos {
+			t.Errorf("Expected %s at %v, got %v", city, pos, layout.Positions[city])
+		}
+	}
+	if layout.Width != 5 || layout.Height != 2 {
+		t.Errorf("Expected a 5x2 grid, got %dx%d", layout.Width, layout.Height)
+	}
+}
+
+func TestLayoutWorldReportsConflicts(t *testing.T) {
+	// Qux is both north of Foo and east of Bar, which is itself north of Foo
+	csvData := "Foo north=Bar east=Baz\nBar east=Qux\nBaz north=Zap\nQux south=Foo\nZap up=Foo\n"
+	world, _ := BuildWorldFromRecords(NewCSVReader(strings.NewReader(csvData)).ReadAll())
+	layout := LayoutWorld(world)
+
+	expected := []string{
+		"Qux south=Foo: puts Qux in the same place as Bar",
+		"Bar east=Qux: puts Qux 1 east of where the other roads put it",
+		"Zap up=Foo: isn't a compass direction",
+	}
+	if len(layout.Conflicts) != len(expected) {
+		t.Fatalf("Expected %d conflicts, got %v", len(expected), layout.Conflicts)
+	}
+	for i, conflict := range layout.Conflicts {
+		if conflict.String() != expected[i] {
+			t.Errorf("Expected conflict %q, got %q", expected[i], conflict.String())
+		}
+	}
+}
@@ -1,78 +1,367 @@
 package main
 
 import (
+	"bytes"
 	"flag"
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"time"
 )
 
+const defaultMapDataFn = "assets/world_map_small.txt"
+
+// Help for the options shared by the commands which play games
+const (
+	terminationUsage = "when to end a game: \"moves\" once every monster has moved 10000 times, or \"iterations\" after 10000 steps"
+	strategyUsage    = "how monsters pick their roads, one of uniform, momentum, no-backtrack, seek, flee or hubs, or a comma separated list given to the monsters in turn"
+	combatUsage      = "what happens when a city fills up: \"annihilation\", \"random-winner\", \"strength\", \"health\" or \"city-survives\""
+	stepModeUsage    = "how monsters move in each step: \"sequential\" one at a time in id order, or \"simultaneous\" all at once, fighting when they pass on a road"
+	speciesUsage     = "input file path containing species of monsters and the mix of them to spawn, as JSON"
+	mixUsage         = "how many monsters of each species to spawn, e.g. giant=2,imp=50 (default: the mix in the -species file)"
+)
+
+// commands are the modes of the program other than playing a game, selected by the first cli arg
+// e.g. ./monsters replay -events out.ndjson
+var commands = map[string]func(args []string) error{
+	"batch":    runBatch,
+	"generate": runGenerate,
+	"layout":   runLayout,
+	"replay":   runReplay,
+	"serve":    runServe,
+	"sweep":    runSweep,
+	"validate": runValidate,
+}
+
 func main() {
-	defaultMapDataFn := "assets/world_map_small.txt"
+	// Errors are reported as plain diagnostics
+	log.SetFlags(0)
+
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
 
 	// Get cli flags
-	initialMonsterCount := flag.Uint("n", 0, "specify the number of monsters you want to start with (n > 0)")
+	initialMonsterCount := flag.Uint("n", 0, "specify the number of monsters you want to start with (n > 0, default: the size of the spawn mix)")
 	mapDataFn := flag.String("d", defaultMapDataFn, "input file path containing data used to build the game map")
 	outputDataFn := flag.String("o", "", "output file path to write the world state after the game, writes to stdout as default")
+	seed := flag.Int64("seed", 0, "seed for the random number generator, use the same seed, map and monster count to replay a game (default: time based)")
+	termination := flag.String("termination", "moves", terminationUsage)
+	strategy := flag.String("strategy", "uniform", strategyUsage)
+	combat := flag.String("combat", "annihilation", combatUsage)
+	stepMode := flag.String("step-mode", "sequential", stepModeUsage)
+	speciesFn := flag.String("species", "", speciesUsage)
+	mixSpec := flag.String("mix", "", mixUsage)
+	inputFormat := flag.String("if", "", "format of the map data file, \"csv\" or \"json\" (default: guessed from the file extension)")
+	outputFormat := flag.String("of", "", "format to write the world state in, \"csv\", \"json\" or \"dot\" (default: guessed from the output file extension)")
+	dotStartFn := flag.String("dot-start", "", "output file path to draw the world as a Graphviz DOT graph once the monsters are placed")
+	dotEndFn := flag.String("dot-end", "", "output file path to draw the world as a Graphviz DOT graph after the game, including destroyed cities")
+	gridFn := flag.String("grid", "", "output file path to draw the world on a grid after the game, laid out from the directions of its roads")
+	unicode := flag.Bool("unicode", false, "draw the -grid and -tui with box drawing characters instead of ASCII")
+	showTUI := flag.Bool("tui", false, "watch the game in the terminal, redrawing the world after every step")
+	tuiDelay := flag.Duration("tui-delay", 200*time.Millisecond, "time between steps when watching the game with -tui")
+	lossless := flag.Bool("lossless", false, "write every surviving city, including those with no roads left, so that the output can be loaded as the remaining world")
+	parseMode := flag.String("parse", "permissive", "how to treat problems in the map: \"permissive\" accepts them, \"strict\" rejects the map, \"lenient\" skips bad lines with a warning")
+	eventsFn := flag.String("events", "", "output file path to write every game event to as newline delimited JSON, for use with the replay command")
+	resumeFn := flag.String("resume", "", "input file path of a checkpoint to continue a game from, the map, monsters, seed and rules are all taken from it")
+	checkpointFn := flag.String("checkpoint", "", "output file path to save the game to when it ends, or when -tui is quit, so that it can be continued with -resume")
+	checkpointEvery := flag.Int("checkpoint-every", 0, "also save the game to the -checkpoint file every this many steps")
 	flag.Parse()
 
-	// Print usage info if no cli arg is provided
+	// Monsters are given species from the spawn mix, if there is one
+	mix, err := loadSpawnMix(*speciesFn, *mixSpec)
+	if err != nil {
+		log.Fatal(err)
+	}
 	if *initialMonsterCount == 0 {
+		*initialMonsterCount = mix.Total()
+	}
+	if err := checkMonsterCount(*initialMonsterCount, mix); err != nil {
+		log.Fatal(err)
+	}
+
+	// Print usage info if no cli arg is provided
+	if *initialMonsterCount == 0 && *resumeFn == "" {
 		flag.Usage()
 		return
 	}
 
-	// Open the map data file
-	file, err := os.Open(*mapDataFn)
-	defer file.Close()
+	// Check the output format before spending time on a game
+	if *outputFormat != "" {
+		if err := checkOutputFormat(*outputFormat); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *checkpointEvery > 0 && *checkpointFn == "" {
+		log.Fatal("-checkpoint-every needs a -checkpoint file to save the game to")
+	}
+
+	var game *MonsterGame
+	var worldOfX *World
+	if *resumeFn != "" {
+		// Continue a game from where it was saved, with the world as it was then
+		checkpoint, err := loadCheckpoint(*resumeFn)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if game, err = ResumeGame(checkpoint); err != nil {
+			log.Fatalf("%s: %v", *resumeFn, err)
+		}
+		worldOfX = game.World()
+	} else {
+		// Build world graph/map based on map data records
+		mode, err := ParseLoadMode(*parseMode)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if worldOfX, err = loadWorld(*mapDataFn, *inputFormat, mode); err != nil {
+			log.Fatal(err)
+		}
+
+		*seed = resolveSeed(flag.CommandLine, *seed)
+	}
+
+	style := ASCIIGrid
+	if *unicode {
+		style = UnicodeGrid
+	}
+
+	// Report destroyed cities to stdout, holding the messages back while the terminal visualisation owns the screen
+	var textOutput io.Writer = os.Stdout
+	var heldOutput bytes.Buffer
+	var tui *TUI
+	if *showTUI {
+		textOutput = &heldOutput
+		tui = NewTUI(worldOfX, style, *tuiDelay, os.Stdout)
+	}
+	observers := []GameObserver{NewTextObserver(textOutput)}
+	if tui != nil {
+		observers = append(observers, tui)
+	}
+	opts := []GameOption{WithSeed(*seed)}
+
+	rule, err := ParseTermination(*termination)
+	if err != nil {
+		log.Fatal(err)
+	}
+	opts = append(opts, WithTermination(rule))
+	strategies, err := ParseStrategies(*strategy)
 	if err != nil {
 		log.Fatal(err)
 	}
+	opts = append(opts, WithStrategies(strategies...))
+	combatRule, err := ParseCombatRule(*combat)
+	if err != nil {
+		log.Fatal(err)
+	}
+	steps, err := ParseStepMode(*stepMode)
+	if err != nil {
+		log.Fatal(err)
+	}
+	opts = append(opts, WithCombat(combatRule), WithSpawnMix(mix), WithStepMode(steps))
+
+	// Optionally record every event so that the game can be replayed
+	var eventLog *EventLogWriter
+	if *eventsFn != "" {
+		file, err := os.Create(*eventsFn)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		eventLog = NewEventLogWriter(file)
+		observers = append(observers, eventLog)
+	}
+
+	if game == nil {
+		// Create a new game instance using map
+		for _, o := range observers {
+			opts = append(opts, WithObserver(o))
+		}
+		game = NewMonsterGame(worldOfX, 10000, *initialMonsterCount, opts...)
+	} else {
+		// A resumed game keeps the rules it was started with, only the observers are new
+		for _, o := range observers {
+			game.Subscribe(o)
+		}
+	}
+
+	if *dotStartFn != "" {
+		if err := writeWorld(worldOfX, *dotStartFn, "dot", false); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Save the game along the way if asked to
+	autosave := func(game *MonsterGame) error {
+		if *checkpointEvery > 0 && game.Steps()%*checkpointEvery == 0 {
+			return saveCheckpoint(game, *checkpointFn)
+		}
+		return nil
+	}
+	if tui != nil {
+		// Run the game step by step under the user's control, they may quit before it finishes
+		keys, restoreTerminal, err := readKeys()
+		if err != nil {
+			log.Fatal(err)
+		}
+		tui.SetAfterStep(autosave)
+		err = tui.Run(game, keys)
+		restoreTerminal()
+		os.Stdout.WriteString(ansiClearScreen)
+		os.Stdout.Write(heldOutput.Bytes())
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		// Run the game to completion
+		for game.Step() {
+			if err := autosave(game); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+	if *checkpointFn != "" {
+		if err := saveCheckpoint(game, *checkpointFn); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *dotEndFn != "" {
+		if err := writeWorld(worldOfX, *dotEndFn, "dot", false); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *gridFn != "" {
+		if err := writeGrid(worldOfX, *gridFn, style); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if eventLog != nil && eventLog.Err() != nil {
+		log.Fatal(eventLog.Err())
+	}
+
+	os.Stdout.WriteString("\n")
+
+	// Output what's left of the world
+	if err := writeWorld(worldOfX, *outputDataFn, *outputFormat, *lossless); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// resolveSeed returns the value of the -seed flag in flags if it was given
+// Otherwise it picks a seed from the clock and reports it, so that the run can be replayed
+func resolveSeed(flags *flag.FlagSet, seed int64) int64 {
+	seedSet := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			seedSet = true
+		}
+	})
+	if !seedSet {
+		seed = time.Now().UnixNano()
+		fmt.Fprintf(os.Stderr, "Using seed %d\n", seed)
+	}
+	return seed
+}
+
+// loadWorld reads map data from a file and builds a World from it, warnings about the data are written to stderr
+// The format of the file is guessed from its extension unless one is given
+func loadWorld(mapDataFn string, format string, mode LoadMode) (*World, error) {
+	// Open the map data file
+	file, err := os.Open(mapDataFn)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
 
 	// WorldStateReader interface keeps the process of getting input data generic
-	var r WorldStateReader
-	// currently only CSV is used
-	r = NewCSVReader(file)
+	r, err := openWorldStateReader(mapDataFn, format, file)
+	if err != nil {
+		return nil, err
+	}
 	// Read records in the map data file
 	inputChannel := r.ReadAll()
 	// Build world graph/map based on map data records
-	worldOfX, err := BuildWorldFromRecords(inputChannel)
+	world, report, err := LoadWorld(inputChannel, mode)
+	if loadErr, ok := err.(*LoadError); ok {
+		for _, problem := range loadErr.Problems {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", mapDataFn, problem)
+		}
+		return nil, fmt.Errorf("%s: rejected, %d problems found", mapDataFn, len(loadErr.Problems))
+	}
 	if err != nil {
-		log.Fatal(err)
+		// Name the file so that diagnostics read like "map.txt: line 3, column 5: ..."
+		return nil, fmt.Errorf("%s: %v", mapDataFn, err)
 	}
+	for _, warning := range report.Warnings {
+		fmt.Fprintf(os.Stderr, "%s: warning: %s\n", mapDataFn, warning)
+	}
+	if report.Skipped > 0 {
+		fmt.Fprintf(os.Stderr, "%s: skipped %d of %d lines\n", mapDataFn, report.Skipped, report.Records)
+	}
+	return world, nil
+}
 
-	// Create a new game instance using map, redirecting output to stdout
-	game := NewMonsterGame(worldOfX, 10000, *initialMonsterCount, os.Stdout)
-
-	// Run the game to completion
-	game.Start()
+// openWorldStateReader creates a reader for map data in a format, guessing the format from the file path if it isn't given
+func openWorldStateReader(mapDataFn string, format string, r io.Reader) (WorldStateReader, error) {
+	if format == "" {
+		format = FormatFromPath(mapDataFn)
+	}
+	return NewWorldStateReader(format, r)
+}
 
-	os.Stdout.WriteString("\n")
+// writeWorld writes what's left of the world to a file, or to stdout if no file path is given
+// The format is guessed from the file extension unless one is given, stdout defaults to csv
+// Cities with no roads left are only written if lossless is set, DOT graphs always include every city to show what happened
+func writeWorld(w *World, outputDataFn string, format string, lossless bool) error {
+	if format == "" {
+		format = FormatFromPath(outputDataFn)
+	}
+	// Fail before the output file is created
+	if err := checkOutputFormat(format); err != nil {
+		return err
+	}
 
-	// WorldStateWriter interface keeps the process of getting input data generic
-	var w WorldStateWriter
 	// output writer
 	var target io.Writer
 
-	if *outputDataFn != "" {
+	if outputDataFn != "" {
 		// Create the map data output file
 		// Contents will be overwritten!
-		file, err := os.Create(*outputDataFn)
-		defer file.Close()
+		file, err := os.Create(outputDataFn)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
+		defer file.Close()
 		target = file
 	} else {
 		// writing the results to stdout as default
 		target = os.Stdout
 	}
 
-	// currently only CSV format is used
-	w = NewCSVWriter(target)
+	// WorldStateWriter interface keeps the process of getting input data generic
+	writer, err := NewWorldStateWriter(format, target)
+	if err != nil {
+		return err
+	}
 
-	// Output what's left of the world
-	outputChannel := GetRemainingWorldRecords(worldOfX)
 	// Store the records somewhere
-	w.WriteAll(outputChannel)
+	switch {
+	case format == "dot":
+		writer.WriteAll(GetWorldSnapshotRecords(w))
+	case lossless:
+		writer.WriteAll(GetLosslessWorldRecords(w))
+	default:
+		writer.WriteAll(GetRemainingWorldRecords(w))
+	}
+	return nil
 }
@@ -2,6 +2,8 @@ package main
 
 import (
 	"errors"
+	"math/rand"
+	"sort"
 	"strings"
 )
 
@@ -19,11 +21,42 @@ var (
 // MonsterID is the identifier for monsters
 type MonsterID uint
 
+// MonsterStatus describes whether a monster can still take part in the game
+type MonsterStatus int
+
+const (
+	// StatusActive monsters are alive and have roads to follow
+	StatusActive MonsterStatus = iota
+	// StatusTrapped monsters are alive but every road out of their city leads to a destroyed city
+	StatusTrapped
+	// StatusDead monsters have been killed in a fight
+	StatusDead
+)
+
+func (s MonsterStatus) String() string {
+	switch s {
+	case StatusActive:
+		return "active"
+	case StatusTrapped:
+		return "trapped"
+	case StatusDead:
+		return "dead"
+	}
+	return "unknown"
+}
+
 // Monster represents a monster in the game
 type Monster struct {
 	ID       MonsterID
 	name     string
 	location CityName
+	moves    int              // Number of times the monster has travelled along a road
+	status   MonsterStatus    // Whether the monster is active, trapped or dead
+	strategy MovementStrategy // How the monster picks its next road, uniformly at random if nil
+	lastRoad *Road            // The road the monster last travelled along, nil before its first move
+	strength int              // How strong the monster is in a fight, under combat rules which use it
+	health   int              // Damage the monster can still take, under combat rules which use it
+	species  *Species         // The kind of monster, nil for monsters as described in the rules
 }
 
 // SetLocation changes the monster's location
@@ -36,6 +69,97 @@ func (m *Monster) Location() CityName {
 	return m.location
 }
 
+// Moves returns the number of times the monster has travelled along a road, being placed on the map is not a move
+func (m *Monster) Moves() int {
+	return m.moves
+}
+
+// AddMove counts a trip along a road
+func (m *Monster) AddMove() {
+	m.moves++
+}
+
+// Status returns whether the monster is active, trapped or dead
+func (m *Monster) Status() MonsterStatus {
+	return m.status
+}
+
+// SetStatus changes whether the monster is active, trapped or dead
+func (m *Monster) SetStatus(status MonsterStatus) {
+	m.status = status
+}
+
+// Strategy returns how the monster picks its next road
+func (m *Monster) Strategy() MovementStrategy {
+	if m.strategy == nil {
+		return UniformStrategy{}
+	}
+	return m.strategy
+}
+
+// SetStrategy changes how the monster picks its next road
+func (m *Monster) SetStrategy(strategy MovementStrategy) {
+	m.strategy = strategy
+}
+
+// LastRoad returns the road the monster last travelled along, nil before its first move
+func (m *Monster) LastRoad() *Road {
+	return m.lastRoad
+}
+
+// SetLastRoad records the road the monster has just travelled along
+func (m *Monster) SetLastRoad(road *Road) {
+	m.lastRoad = road
+}
+
+// Strength returns how strong the monster is in a fight
+func (m *Monster) Strength() int {
+	return m.strength
+}
+
+// SetStrength changes how strong the monster is in a fight
+func (m *Monster) SetStrength(strength int) {
+	m.strength = strength
+}
+
+// Health returns the damage the monster can still take before it dies
+func (m *Monster) Health() int {
+	return m.health
+}
+
+// SetHealth changes the damage the monster can still take
+func (m *Monster) SetHealth(health int) {
+	m.health = health
+}
+
+// Species returns the kind of monster, nil for monsters as described in the rules
+func (m *Monster) Species() *Species {
+	return m.species
+}
+
+// SetSpecies makes the monster one of a species, giving it the species' health and its attack as its strength
+func (m *Monster) SetSpecies(species *Species) {
+	m.species = species
+	m.health = species.Health
+	m.strength = species.Attack
+}
+
+// Speed returns the number of roads the monster travels along in each step
+func (m *Monster) Speed() int {
+	if m.species == nil {
+		return 1
+	}
+	return m.species.Speed
+}
+
+// Weight returns how much of a city's capacity the monster takes up
+func (m *Monster) Weight() int {
+	if m.species == nil {
+		return 1
+	}
+	return m.species.Weight
+}
+
 // Name return the name of the monster
 func (m *Monster) Name() string {
 	return m.name
@@ -43,15 +167,26 @@ func (m *Monster) Name() string {
 
 // NewMonster creates a monster with a randomly generated name
 func NewMonster(id uint) *Monster {
+	return NewMonsterWithName(id, generateMonsterName(seededRand))
+}
+
+// NewMonsterWithName creates a monster with the given name
+func NewMonsterWithName(id uint, name string) *Monster {
 	return &Monster{
 		ID:   MonsterID(id),
-		name: strings.Title(GenerateIdentifier(monsterNameLength)),
+		name: name,
 	}
 }
 
+// generateMonsterName draws a capitalised monster name from the random source
+func generateMonsterName(r *rand.Rand) string {
+	return strings.Title(GenerateIdentifierFrom(r, monsterNameLength))
+}
+
 // MonsterCollection is a store of monsters
 type MonsterCollection struct {
 	monsters map[MonsterID]*Monster
+	order    []MonsterID // Monster ids in ascending order, so that iteration is deterministic
 }
 
 // Add adds a monster to the store if it is not already present
@@ -60,6 +195,10 @@ func (mc *MonsterCollection) Add(monster *Monster) error {
 		return ErrMonsterDuplicateID
 	}
 	mc.monsters[monster.ID] = monster
+	i := mc.index(monster.ID)
+	mc.order = append(mc.order, 0)
+	copy(mc.order[i+1:], mc.order[i:])
+	mc.order[i] = monster.ID
 	return nil
 }
 
@@ -69,9 +208,22 @@ func (mc *MonsterCollection) Remove(monster *Monster) error {
 		return ErrMonsterNotFound
 	}
 	delete(mc.monsters, monster.ID)
+	i := mc.index(monster.ID)
+	mc.order = append(mc.order[:i], mc.order[i+1:]...)
 	return nil
 }
 
+// Contains checks whether a monster with the given id is in the store
+func (mc *MonsterCollection) Contains(id MonsterID) bool {
+	_, ok := mc.monsters[id]
+	return ok
+}
+
+// Get returns a monster by its id, or nil if it isn't in the store
+func (mc *MonsterCollection) Get(id MonsterID) *Monster {
+	return mc.monsters[id]
+}
+
 // Length returns the number of monsters in the store
 func (mc *MonsterCollection) Length() int {
 	return len(mc.monsters)
@@ -82,9 +234,18 @@ func (mc *MonsterCollection) IsEmpty() bool {
 	return mc.Length() == 0
 }
 
-// GetAll returns a map of monsters by id
-func (mc *MonsterCollection) GetAll() map[MonsterID]*Monster {
-	return mc.monsters
+// GetAll returns a snapshot of the monsters in the store ordered by id
+func (mc *MonsterCollection) GetAll() []*Monster {
+	monsters := make([]*Monster, len(mc.order))
+	for i, id := range mc.order {
+		monsters[i] = mc.monsters[id]
+	}
+	return monsters
+}
+
+// index finds the position of an id in the ordered ids, or the position at which it would be inserted
+func (mc *MonsterCollection) index(id MonsterID) int {
+	return sort.Search(len(mc.order), func(i int) bool { return mc.order[i] >= id })
 }
 
 // NewMonsterCollection creates a new monster store
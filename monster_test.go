@@ -1 +1,29 @@
 package main
+
+import "testing"
+
+func TestMonsterCollectionOrder(t *testing.T) {
+	monsters := NewMonsterCollection()
+	for _, id := range []uint{5, 1, 3, 0, 4, 2} {
+		monsters.Add(NewMonsterWithName(id, "Monster"))
+	}
+	monsters.Remove(monsters.Get(3))
+
+	expected := []MonsterID{0, 1, 2, 4, 5}
+	all := monsters.GetAll()
+	if len(all) != len(expected) {
+		t.Fatalf("Expected %d monsters, got %d", len(expected), len(all))
+	}
+	for i, monster := range all {
+		if monster.ID != expected[i] {
+			t.Errorf("Expected monster %d at position %d, got %d", expected[i], i, monster.ID)
+		}
+	}
+
+	if monsters.Contains(3) {
+		t.Errorf("Removed monster should not be in the collection")
+	}
+	if err := monsters.Add(NewMonsterWithName(1, "Duplicate")); err != ErrMonsterDuplicateID {
+		t.Errorf("Adding a duplicate id should return ErrMonsterDuplicateID")
+	}
+}
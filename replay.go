@@ -0,0 +1,160 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Replay rebuilds the state of a world by applying the recorded events of a game to it
+type Replay struct {
+	world     *World
+	monsters  *MonsterCollection // Monsters which are still alive
+	observers []GameObserver     // Receivers of the replayed events
+}
+
+// Apply updates the world with a recorded event and passes it on to the replay's observers
+func (r *Replay) Apply(event GameEvent) error {
+	switch e := event.(type) {
+	case MonsterSpawned:
+		monster := NewMonsterWithName(uint(e.Monster.ID), e.Monster.Name)
+		if err := r.monsters.Add(monster); err != nil {
+			return fmt.Errorf("Step %d: cannot spawn monster %s: %v", e.Step, e.Monster.Name, err)
+		}
+		if err := r.enter(monster, e.City); err != nil {
+			return fmt.Errorf("Step %d: cannot spawn monster %s: %v", e.Step, e.Monster.Name, err)
+		}
+	case MonsterMoved:
+		monster := r.monsters.Get(e.Monster.ID)
+		if monster == nil || monster.Location() != e.From {
+			return fmt.Errorf("Step %d: monster %s is not in %s", e.Step, e.Monster.Name, e.From)
+		}
+		r.world.GetCity(e.From).RemoveMonster(monster)
+		if err := r.enter(monster, e.To); err != nil {
+			return fmt.Errorf("Step %d: cannot move monster %s: %v", e.Step, e.Monster.Name, err)
+		}
+		monster.AddMove()
+	case MonsterTrapped:
+		if monster := r.monsters.Get(e.Monster.ID); monster != nil {
+			monster.SetStatus(StatusTrapped)
+		}
+	case CityDestroyed:
+		city := r.world.GetCity(e.City)
+		if city == nil {
+			return fmt.Errorf("Step %d: city %s doesn't exist", e.Step, e.City)
+		}
+		if err := city.Destroy(); err != nil {
+			return fmt.Errorf("Step %d: cannot destroy %s: %v", e.Step, e.City, err)
+		}
+		// The dead monsters stay in the destroyed city, just as they do in a game
+		for _, ref := range e.Monsters {
+			if monster := r.monsters.Get(ref.ID); monster != nil {
+				monster.SetStatus(StatusDead)
+				r.monsters.Remove(monster)
+			}
+		}
+	case BattleFought:
+		city := r.world.GetCity(e.City)
+		if city == nil {
+			return fmt.Errorf("Step %d: city %s doesn't exist", e.Step, e.City)
+		}
+		// Monsters killed in a city which still stands are removed from it, as they are in a game
+		for _, ref := range e.Monsters {
+			if monster := r.monsters.Get(ref.ID); monster != nil {
+				monster.SetStatus(StatusDead)
+				city.RemoveMonster(monster)
+				r.monsters.Remove(monster)
+			}
+		}
+	case RoadBattleFought:
+		// Monsters killed on a road never arrive, they are removed from the city they set out from
+		for _, ref := range e.Monsters {
+			if monster := r.monsters.Get(ref.ID); monster != nil {
+				monster.SetStatus(StatusDead)
+				r.world.GetCity(monster.Location()).RemoveMonster(monster)
+				r.monsters.Remove(monster)
+			}
+		}
+	}
+	for _, o := range r.observers {
+		o.OnEvent(event)
+	}
+	return nil
+}
+
+// enter places a monster in a city without triggering a fight, the log records the outcome separately
+func (r *Replay) enter(monster *Monster, cityName CityName) error {
+	city := r.world.GetCity(cityName)
+	if city == nil {
+		return fmt.Errorf("city %s doesn't exist", cityName)
+	}
+	if city.Destroyed {
+		return ErrCityDestroyed
+	}
+	city.Monsters.Add(monster)
+	monster.SetLocation(cityName)
+	return nil
+}
+
+// NewReplay creates a Replay which applies events to the world w and passes them on to the observers
+func NewReplay(w *World, observers ...GameObserver) *Replay {
+	return &Replay{world: w, monsters: NewMonsterCollection(), observers: observers}
+}
+
+// ReplayEventLog applies the events read by lr to a Replay up to and including step lastStep, or all events when lastStep is negative
+func ReplayEventLog(replay *Replay, lr *EventLogReader, lastStep int) error {
+	for {
+		event, err := lr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if lastStep >= 0 && event.StepNumber() > lastStep {
+			return nil
+		}
+		if err := replay.Apply(event); err != nil {
+			return err
+		}
+	}
+}
+
+// runReplay implements the replay command, rebuilding a recorded game from its map and event log
+func runReplay(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	mapDataFn := flags.String("d", defaultMapDataFn, "input file path containing the map the game was played on")
+	inputFormat := flags.String("if", "", "format of the map data file, \"csv\" or \"json\" (default: guessed from the file extension)")
+	outputFormat := flags.String("of", "", "format to write the world state in, \"csv\", \"json\" or \"dot\" (default: guessed from the output file extension)")
+	eventsFn := flags.String("events", "", "event log file written with the -events flag of a game (required)")
+	lastStep := flags.Int("step", -1, "rebuild the world as it was at the end of this step, replays the whole game as default")
+	outputDataFn := flags.String("o", "", "output file path to write the rebuilt world state, writes to stdout as default")
+	lossless := flags.Bool("lossless", false, "write every surviving city, including those with no roads left")
+	flags.Parse(args)
+
+	if *eventsFn == "" {
+		flags.Usage()
+		return nil
+	}
+
+	worldOfX, err := loadWorld(*mapDataFn, *inputFormat, LoadPermissive)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(*eventsFn)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Reprint the destruction messages exactly as the game did
+	replay := NewReplay(worldOfX, NewTextObserver(os.Stdout))
+	if err := ReplayEventLog(replay, NewEventLogReader(file), *lastStep); err != nil {
+		return err
+	}
+
+	os.Stdout.WriteString("\n")
+	return writeWorld(worldOfX, *outputDataFn, *outputFormat, *lossless)
+}
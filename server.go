@@ -0,0 +1,565 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxUploadedMapSize is the largest map accepted when creating a game over HTTP
+const maxUploadedMapSize = 32 * 1024 * 1024
+
+// defaultServedIterations is the iteration limit of games created over HTTP unless one is given, as in the rules
+const defaultServedIterations = 10000
+
+// Limits on the games created over HTTP, so that a single request can't tie up the server
+const (
+	maxServedMonsters   = 100000
+	maxServedIterations = 1000000
+)
+
+// runUnlockSteps is how many steps a run without a delay plays before unlocking its session for other requests
+const runUnlockSteps = 100
+
+// GameServer plays games on behalf of HTTP clients
+// Every game is held in its own session with its own lock, so that many games can be played at once
+//
+//	POST   /games                 upload a map and create a game, e.g. ?n=10&seed=1&max=10000&format=json&strategy=seek&combat=strength&step-mode=simultaneous
+//	GET    /games                 list the games
+//	GET    /games/{id}            describe a game
+//	DELETE /games/{id}            forget a game, ending the streams of its spectators
+//	POST   /games/{id}/step       play ?n= steps (1 by default), returning the events they produced
+//	POST   /games/{id}/run        play the game to completion, e.g. ?delay=100ms between steps for spectators to follow
+//	GET    /games/{id}/monsters   list every monster, whatever its status
+//	GET    /games/{id}/cities     list every city, including destroyed ones
+//	GET    /games/{id}/history    list the cities destroyed and battles fought so far, on roads as well as in cities
+//	GET    /games/{id}/layout     grid positions of the cities worked out from the directions of their roads
+//	GET    /games/{id}/world      what's left of the world as map data, e.g. ?format=json&lossless=true
+//	GET    /games/{id}/events     watch the game as Server-Sent Events, starting with a snapshot of the game
+type GameServer struct {
+	mu       sync.Mutex
+	sessions map[string]*gameSession
+	nextID   int
+}
+
+// gameSession is a game being played over HTTP
+// It observes its game to keep a history of the events the text output reports and to pass events on to spectators
+type gameSession struct {
+	mu         sync.Mutex
+	id         string
+	seed       int64
+	game       *MonsterGame
+	layout     *Layout     // Worked out once as the roads don't change while the game is played
+	history    []GameEvent // Destroyed cities and battles, as reported by the text output
+	collecting bool        // Whether events are kept in pending, only while stepGame plays the steps whose events it returns
+	pending    []GameEvent // Events kept since collecting started
+	ended      *GameEnded
+	spectators map[*spectator]struct{}
+}
+
+// OnEvent records the events of the session's game and sends them to its spectators
+func (s *gameSession) OnEvent(event GameEvent) {
+	s.broadcast(event)
+	switch e := event.(type) {
+	case CityDestroyed, BattleFought, RoadBattleFought:
+		s.history = append(s.history, e)
+	case GameEnded:
+		s.ended = &e
+	}
+	if s.collecting {
+		s.pending = append(s.pending, event)
+	}
+}
+
+// collectEvents starts keeping the events of the session's game until takeEvents is called
+func (s *gameSession) collectEvents() {
+	s.collecting = true
+}
+
+// takeEvents stops keeping events and returns the events kept since collectEvents was called
+func (s *gameSession) takeEvents() []GameEvent {
+	events := s.pending
+	s.collecting, s.pending = false, nil
+	return events
+}
+
+// gameSummary is the JSON description of a game
+type gameSummary struct {
+	ID              string    `json:"id"`
+	Seed            int64     `json:"seed"`
+	Steps           int       `json:"steps"`
+	Done            bool      `json:"done"`
+	EndReason       EndReason `json:"end_reason,omitempty"`
+	Monsters        int       `json:"monsters"`
+	ActiveMonsters  int       `json:"active_monsters"`
+	Cities          int       `json:"cities"`
+	DestroyedCities int       `json:"destroyed_cities"`
+}
+
+// monsterSummary is the JSON description of a monster
+type monsterSummary struct {
+	ID      MonsterID `json:"id"`
+	Name    string    `json:"name"`
+	City    CityName  `json:"city"`
+	Moves   int       `json:"moves"`
+	Status  string    `json:"status"`
+	Species string    `json:"species,omitempty"`
+	Health  int       `json:"health,omitempty"`
+}
+
+// citySummary is the JSON description of a city
+type citySummary struct {
+	Name      CityName     `json:"name"`
+	Destroyed bool         `json:"destroyed"`
+	Capacity  int          `json:"capacity"`
+	Monsters  []MonsterRef `json:"monsters"`
+	Roads     []jsonRoad   `json:"roads"`
+}
+
+// layoutSummary is the JSON description of a Layout
+type layoutSummary struct {
+	Width     int            `json:"width"`
+	Height    int            `json:"height"`
+	Cities    []cityPosition `json:"cities"`
+	Conflicts []string       `json:"conflicts"`
+}
+
+// cityPosition is the JSON description of the place of a city on the grid
+type cityPosition struct {
+	Name CityName `json:"name"`
+	X    int      `json:"x"`
+	Y    int      `json:"y"`
+}
+
+// stepResult is the JSON response to playing some steps of a game
+type stepResult struct {
+	Game   gameSummary    `json:"game"`
+	Events []*eventRecord `json:"events"`
+}
+
+// summary describes the session's game, the session must be locked
+func (s *gameSession) summary() gameSummary {
+	summary := gameSummary{
+		ID:             s.id,
+		Seed:           s.seed,
+		Steps:          s.game.Steps(),
+		Done:           s.game.Done(),
+		Monsters:       s.game.Monsters.Length(),
+		ActiveMonsters: s.game.ActiveMonsters.Length(),
+	}
+	if s.ended != nil {
+		summary.EndReason = s.ended.Reason
+	}
+	for _, city := range s.game.World().GetCities() {
+		summary.Cities++
+		if city.Destroyed {
+			summary.DestroyedCities++
+		}
+	}
+	return summary
+}
+
+// NewGameServer creates a GameServer with no games
+func NewGameServer() *GameServer {
+	return &GameServer{sessions: make(map[string]*gameSession)}
+}
+
+// ServeHTTP routes a request to the handler for its path
+func (gs *GameServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] != "games" || len(parts) > 3 {
+		writeHTTPError(w, http.StatusNotFound, fmt.Errorf("No such resource %s", r.URL.Path))
+		return
+	}
+	if len(parts) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			gs.listGames(w, r)
+		case http.MethodPost:
+			gs.createGame(w, r)
+		default:
+			writeMethodNotAllowed(w, http.MethodGet, http.MethodPost)
+		}
+		return
+	}
+
+	session := gs.session(parts[1])
+	if session == nil {
+		writeHTTPError(w, http.StatusNotFound, fmt.Errorf("No such game %s", parts[1]))
+		return
+	}
+	if len(parts) == 2 {
+		switch r.Method {
+		case http.MethodGet:
+			session.mu.Lock()
+			defer session.mu.Unlock()
+			writeJSON(w, http.StatusOK, session.summary())
+		case http.MethodDelete:
+			session.mu.Lock()
+			session.unwatchAll()
+			gs.mu.Lock()
+			delete(gs.sessions, session.id)
+			gs.mu.Unlock()
+			session.mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeMethodNotAllowed(w, http.MethodGet, http.MethodDelete)
+		}
+		return
+	}
+
+	handlers := map[string]struct {
+		method  string
+		handler func(http.ResponseWriter, *http.Request, *gameSession)
+	}{
+		"step":     {http.MethodPost, stepGame},
+		"run":      {http.MethodPost, runGame},
+		"monsters": {http.MethodGet, listMonsters},
+		"cities":   {http.MethodGet, listCities},
+		"history":  {http.MethodGet, listHistory},
+		"layout":   {http.MethodGet, describeLayout},
+		"world":    {http.MethodGet, writeRemainingWorld},
+	}
+	// Event streams lock the session themselves as they last while the game is played
+	if parts[2] == "events" {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, http.MethodGet)
+			return
+		}
+		streamEvents(w, r, session)
+		return
+	}
+
+	route, ok := handlers[parts[2]]
+	if !ok {
+		writeHTTPError(w, http.StatusNotFound, fmt.Errorf("No such resource %s", r.URL.Path))
+		return
+	}
+	if r.Method != route.method {
+		writeMethodNotAllowed(w, route.method)
+		return
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	route.handler(w, r, session)
+}
+
+// session finds a game by its ID, returning nil if there is no such game
+func (gs *GameServer) session(id string) *gameSession {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	return gs.sessions[id]
+}
+
+// listGames describes every game, in the order they were created
+func (gs *GameServer) listGames(w http.ResponseWriter, r *http.Request) {
+	gs.mu.Lock()
+	sessions := make([]*gameSession, 0, len(gs.sessions))
+	for _, session := range gs.sessions {
+		sessions = append(sessions, session)
+	}
+	gs.mu.Unlock()
+	sort.Slice(sessions, func(i, j int) bool {
+		a, _ := strconv.Atoi(sessions[i].id)
+		b, _ := strconv.Atoi(sessions[j].id)
+		return a < b
+	})
+
+	summaries := make([]gameSummary, len(sessions))
+	for i, session := range sessions {
+		session.mu.Lock()
+		summaries[i] = session.summary()
+		session.mu.Unlock()
+	}
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+// createGame builds a world from the map in the request body and places the monsters on it
+func (gs *GameServer) createGame(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	monsterCount, err := strconv.ParseUint(query.Get("n"), 10, 32)
+	if err != nil || monsterCount == 0 || monsterCount > maxServedMonsters {
+		writeHTTPError(w, http.StatusBadRequest, fmt.Errorf("n must be a number of monsters from 1 to %d", maxServedMonsters))
+		return
+	}
+	seed := time.Now().UnixNano()
+	if query.Get("seed") != "" {
+		if seed, err = strconv.ParseInt(query.Get("seed"), 10, 64); err != nil {
+			writeHTTPError(w, http.StatusBadRequest, fmt.Errorf("Invalid seed %q", query.Get("seed")))
+			return
+		}
+	}
+	maxIterations := defaultServedIterations
+	if query.Get("max") != "" {
+		if maxIterations, err = strconv.Atoi(query.Get("max")); err != nil || maxIterations < 0 || maxIterations > maxServedIterations {
+			writeHTTPError(w, http.StatusBadRequest, fmt.Errorf("Invalid iteration limit %q, expected at most %d", query.Get("max"), maxServedIterations))
+			return
+		}
+	}
+	opts := []GameOption{WithSeed(seed)}
+	if query.Get("termination") != "" {
+		rule, err := ParseTermination(query.Get("termination"))
+		if err != nil {
+			writeHTTPError(w, http.StatusBadRequest, err)
+			return
+		}
+		opts = append(opts, WithTermination(rule))
+	}
+	if query.Get("strategy") != "" {
+		strategies, err := ParseStrategies(query.Get("strategy"))
+		if err != nil {
+			writeHTTPError(w, http.StatusBadRequest, err)
+			return
+		}
+		opts = append(opts, WithStrategies(strategies...))
+	}
+	if query.Get("combat") != "" {
+		rule, err := ParseCombatRule(query.Get("combat"))
+		if err != nil {
+			writeHTTPError(w, http.StatusBadRequest, err)
+			return
+		}
+		opts = append(opts, WithCombat(rule))
+	}
+	if query.Get("step-mode") != "" {
+		stepMode, err := ParseStepMode(query.Get("step-mode"))
+		if err != nil {
+			writeHTTPError(w, http.StatusBadRequest, err)
+			return
+		}
+		opts = append(opts, WithStepMode(stepMode))
+	}
+	mode := LoadPermissive
+	if query.Get("parse") != "" {
+		if mode, err = ParseLoadMode(query.Get("parse")); err != nil {
+			writeHTTPError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	format := query.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	reader, err := NewWorldStateReader(format, http.MaxBytesReader(w, r.Body, maxUploadedMapSize))
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, err)
+		return
+	}
+	world, _, err := LoadWorld(reader.ReadAll(), mode)
+	if err != nil {
+		writeHTTPError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	// The session is only shared once its game is ready
+	session := &gameSession{seed: seed, layout: LayoutWorld(world), spectators: make(map[*spectator]struct{})}
+	session.game = NewMonsterGame(world, maxIterations, uint(monsterCount), append(opts, WithObserver(session))...)
+
+	gs.mu.Lock()
+	gs.nextID++
+	session.id = strconv.Itoa(gs.nextID)
+	gs.sessions[session.id] = session
+	summary := session.summary()
+	gs.mu.Unlock()
+	writeJSON(w, http.StatusCreated, summary)
+}
+
+// stepGame plays ?n= steps of a game, stopping early if it finishes
+func stepGame(w http.ResponseWriter, r *http.Request, session *gameSession) {
+	steps := 1
+	if n := r.URL.Query().Get("n"); n != "" {
+		var err error
+		if steps, err = strconv.Atoi(n); err != nil || steps < 1 {
+			writeHTTPError(w, http.StatusBadRequest, fmt.Errorf("Invalid number of steps %q", n))
+			return
+		}
+	}
+	session.collectEvents()
+	for i := 0; i < steps && session.game.Step(); i++ {
+	}
+
+	events := session.takeEvents()
+	result := stepResult{Game: session.summary(), Events: make([]*eventRecord, 0, len(events))}
+	for _, event := range events {
+		rec, err := newEventRecord(event)
+		if err != nil {
+			writeHTTPError(w, http.StatusInternalServerError, err)
+			return
+		}
+		result.Events = append(result.Events, rec)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// runGame plays a game to completion, its events are left out as there may be millions of them
+// With a ?delay= the session is unlocked between steps, so that spectators can follow and the game can be inspected
+// Without one it is still unlocked every runUnlockSteps steps, so that other requests aren't held up for the whole game
+func runGame(w http.ResponseWriter, r *http.Request, session *gameSession) {
+	var delay time.Duration
+	if d := r.URL.Query().Get("delay"); d != "" {
+		var err error
+		if delay, err = time.ParseDuration(d); err != nil || delay < 0 {
+			writeHTTPError(w, http.StatusBadRequest, fmt.Errorf("Invalid delay %q", d))
+			return
+		}
+	}
+	for steps := 1; session.game.Step(); steps++ {
+		if delay == 0 && steps%runUnlockSteps != 0 {
+			continue
+		}
+		session.mu.Unlock()
+		select {
+		case <-time.After(delay):
+			session.mu.Lock()
+		case <-r.Context().Done():
+			// The game is left where it is, it can be carried on by another request
+			session.mu.Lock()
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, session.summary())
+}
+
+// listMonsters describes every monster in a game in id order
+func listMonsters(w http.ResponseWriter, r *http.Request, session *gameSession) {
+	writeJSON(w, http.StatusOK, monsterSummaries(session.game.Monsters))
+}
+
+// monsterSummaries describes every monster in a collection in id order
+func monsterSummaries(mc *MonsterCollection) []monsterSummary {
+	monsters := mc.GetAll()
+	summaries := make([]monsterSummary, len(monsters))
+	for i, monster := range monsters {
+		summaries[i] = monsterSummary{
+			ID:     monster.ID,
+			Name:   monster.Name(),
+			City:   monster.Location(),
+			Moves:  monster.Moves(),
+			Status: monster.Status().String(),
+		}
+		if species := monster.Species(); species != nil {
+			summaries[i].Species = species.Name
+			summaries[i].Health = monster.Health()
+		}
+	}
+	return summaries
+}
+
+// listCities describes every city in a game in map order
+func listCities(w http.ResponseWriter, r *http.Request, session *gameSession) {
+	writeJSON(w, http.StatusOK, citySummaries(session.game.World()))
+}
+
+// citySummaries describes every city in a world in map order
+func citySummaries(world *World) []citySummary {
+	cities := world.GetCities()
+	summaries := make([]citySummary, len(cities))
+	for i, city := range cities {
+		summaries[i] = citySummary{
+			Name:      city.Name,
+			Destroyed: city.Destroyed,
+			Capacity:  city.Capacity(),
+			Monsters:  refsOf(city.Monsters.GetAll()),
+			Roads:     []jsonRoad{},
+		}
+		for _, road := range world.GetRoads(city.Name) {
+			summaries[i].Roads = append(summaries[i].Roads, jsonRoad{Direction: road.Direction, Destination: road.Destination})
+		}
+	}
+	return summaries
+}
+
+// listHistory lists the cities destroyed and battles fought so far, in the order they happened
+func listHistory(w http.ResponseWriter, r *http.Request, session *gameSession) {
+	history := make([]*eventRecord, len(session.history))
+	for i, event := range session.history {
+		history[i], _ = newEventRecord(event)
+	}
+	writeJSON(w, http.StatusOK, history)
+}
+
+// describeLayout lists the grid positions of the cities of a game in map order, with any roads which couldn't be laid out
+func describeLayout(w http.ResponseWriter, r *http.Request, session *gameSession) {
+	summary := layoutSummary{Width: session.layout.Width, Height: session.layout.Height, Conflicts: []string{}}
+	for _, city := range session.game.World().GetCities() {
+		pos := session.layout.Positions[city.Name]
+		summary.Cities = append(summary.Cities, cityPosition{Name: city.Name, X: pos.X, Y: pos.Y})
+	}
+	for _, conflict := range session.layout.Conflicts {
+		summary.Conflicts = append(summary.Conflicts, conflict.String())
+	}
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// writeRemainingWorld writes what's left of a game's world as map data, as the game would at the end of a run
+func writeRemainingWorld(w http.ResponseWriter, r *http.Request, session *gameSession) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	var buf bytes.Buffer
+	writer, err := NewWorldStateWriter(format, &buf)
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, err)
+		return
+	}
+	world := session.game.World()
+	switch {
+	case format == "dot":
+		writer.WriteAll(GetWorldSnapshotRecords(world))
+	case r.URL.Query().Get("lossless") == "true":
+		writer.WriteAll(GetLosslessWorldRecords(world))
+	default:
+		writer.WriteAll(GetRemainingWorldRecords(world))
+	}
+
+	contentType := "text/plain; charset=utf-8"
+	if format == "json" {
+		contentType = "application/json"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(buf.Bytes())
+}
+
+// writeJSON writes v as the JSON body of a response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeHTTPError writes an error as a JSON body, e.g. {"error": "No such game 3"}
+func writeHTTPError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// writeMethodNotAllowed rejects a request made with a method the resource doesn't support
+func writeMethodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeHTTPError(w, http.StatusMethodNotAllowed, fmt.Errorf("Method not allowed, expected %s", strings.Join(allowed, " or ")))
+}
+
+// runServe is the serve command, which plays games on behalf of HTTP clients until it is stopped
+// The web front end is served alongside the games
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	flags.Parse(args)
+
+	games := NewGameServer()
+	mux := http.NewServeMux()
+	mux.Handle("/games", games)
+	mux.Handle("/games/", games)
+	mux.Handle("/", webHandler())
+
+	log.Printf("Serving games on http://%s/", *addr)
+	return http.ListenAndServe(*addr, mux)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const serverTestMap = "Foo north=Bar east=Baz\nBar south=Foo\nBaz west=Foo\n"
+
+func serverRequest(t *testing.T, server http.Handler, method, target, body string, status int, v interface{}) {
+	if err := tryServerRequest(server, method, target, body, status, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tryServerRequest is serverRequest for goroutines other than the test's own, which mustn't call t.Fatal
+func tryServerRequest(server http.Handler, method, target, body string, status int, v interface{}) error {
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	if rec.Code != status {
+		return fmt.Errorf("%s %s: expected status %d, got %d: %s", method, target, status, rec.Code, rec.Body)
+	}
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			return fmt.Errorf("%s %s: %v", method, target, err)
+		}
+	}
+	return nil
+}
+
+func TestGameServer(t *testing.T) {
+	server := NewGameServer()
+
+	var created gameSummary
+	serverRequest(t, server, http.MethodPost, "/games?n=2&seed=1&max=5", serverTestMap, http.StatusCreated, &created)
+	if created.ID != "1" || created.Monsters != 2 || created.Cities != 3 || created.Steps != 0 {
+		t.Errorf("Unexpected new game %+v", created)
+	}
+
+	var stepped stepResult
+	serverRequest(t, server, http.MethodPost, "/games/1/step?n=1", "", http.StatusOK, &stepped)
+	if stepped.Game.Steps != 1 || len(stepped.Events) == 0 {
+		t.Errorf("Expected the events of one step, got %+v", stepped)
+	}
+
+	var finished gameSummary
+	serverRequest(t, server, http.MethodPost, "/games/1/run", "", http.StatusOK, &finished)
+	if !finished.Done || finished.EndReason == "" {
+		t.Errorf("Expected the game to be finished, got %+v", finished)
+	}
+	// Events are only kept while a step request collects them, a long run would otherwise pile them up
+	session := server.session("1")
+	session.mu.Lock()
+	if len(session.pending) != 0 {
+		t.Errorf("Expected the run's events not to be kept, kept %d", len(session.pending))
+	}
+	session.mu.Unlock()
+
+	var monsters []monsterSummary
+	serverRequest(t, server, http.MethodGet, "/games/1/monsters", "", http.StatusOK, &monsters)
+	if len(monsters) != 2 {
+		t.Errorf("Expected 2 monsters, got %+v", monsters)
+	}
+	var cities []citySummary
+	serverRequest(t, server, http.MethodGet, "/games/1/cities", "", http.StatusOK, &cities)
+	if len(cities) != 3 || cities[0].Name != "Foo" {
+		t.Errorf("Expected the 3 cities in map order, got %+v", cities)
+	}
+
+	serverRequest(t, server, http.MethodGet, "/games/1/step", "", http.StatusMethodNotAllowed, nil)
+	serverRequest(t, server, http.MethodPost, "/games?n=0", serverTestMap, http.StatusBadRequest, nil)
+	serverRequest(t, server, http.MethodPost, "/games?n=1&format=xml", serverTestMap, http.StatusBadRequest, nil)
+	serverRequest(t, server, http.MethodPost, "/games?n=100001", serverTestMap, http.StatusBadRequest, nil)
+	serverRequest(t, server, http.MethodPost, "/games?n=1&max=1000001", serverTestMap, http.StatusBadRequest, nil)
+	serverRequest(t, server, http.MethodDelete, "/games/1", "", http.StatusNoContent, nil)
+	serverRequest(t, server, http.MethodGet, "/games/1", "", http.StatusNotFound, nil)
+}
+
+func TestGameServerConcurrentGames(t *testing.T) {
+	server := NewGameServer()
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var created gameSummary
+			if err := tryServerRequest(server, http.MethodPost, "/games?n=2&seed=1", serverTestMap, http.StatusCreated, &created); err != nil {
+				errs <- err
+				return
+			}
+			if err := tryServerRequest(server, http.MethodPost, "/games/"+created.ID+"/run", "", http.StatusOK, nil); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if t.Failed() {
+		t.FailNow()
+	}
+
+	var games []gameSummary
+	serverRequest(t, server, http.MethodGet, "/games", "", http.StatusOK, &games)
+	if len(games) != 4 {
+		t.Fatalf("Expected 4 games, got %d", len(games))
+	}
+	// Games with the same seed and map play out the same way however they are interleaved
+	for _, game := range games {
+		if !game.Done || game.Steps != games[0].Steps || game.DestroyedCities != games[0].DestroyedCities {
+			t.Errorf("Expected game %s to match game %s, got %+v and %+v", game.ID, games[0].ID, game, games[0])
+		}
+	}
+}
+
+func TestGameServerHistory(t *testing.T) {
+	server := NewGameServer()
+	serverRequest(t, server, http.MethodPost, "/games?n=3&seed=1&combat=city-survives", serverTestMap, http.StatusCreated, nil)
+	serverRequest(t, server, http.MethodPost, "/games?n=4&seed=1&step-mode=simultaneous", serverTestMap, http.StatusCreated, nil)
+	battles := map[string]int{}
+	for _, id := range []string{"1", "2"} {
+		serverRequest(t, server, http.MethodPost, "/games/"+id+"/run", "", http.StatusOK, nil)
+		var history []eventRecord
+		serverRequest(t, server, http.MethodGet, "/games/"+id+"/history", "", http.StatusOK, &history)
+		for _, event := range history {
+			switch event.Type {
+			case eventTypeBattle, eventTypeRoadBattle:
+				battles[event.Type]++
+			case eventTypeDestroyed:
+			default:
+				t.Errorf("Expected only the events of the text output in the history, got %+v", event)
+			}
+		}
+	}
+	if battles[eventTypeBattle] == 0 || battles[eventTypeRoadBattle] == 0 {
+		t.Errorf("Expected battles in cities and on roads in the history, got %v", battles)
+	}
+}
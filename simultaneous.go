@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// StepMode decides how the moves of the monsters within a step are ordered
+type StepMode int
+
+const (
+	// SequentialSteps moves the monsters one at a time in id order, each seeing the moves and fights of those before it
+	SequentialSteps StepMode = iota
+	// SimultaneousSteps has every monster choose its road before any of them moves, then moves them all at once
+	// Monsters arriving in the same city fight together, and monsters passing each other on a road fight on it
+	SimultaneousSteps
+)
+
+// ParseStepMode converts the name of a StepMode, as used on the command line, to a StepMode
+func ParseStepMode(name string) (StepMode, error) {
+	switch name {
+	case "sequential":
+		return SequentialSteps, nil
+	case "simultaneous":
+		return SimultaneousSteps, nil
+	}
+	return SequentialSteps, fmt.Errorf("Unknown step mode %q, expected sequential or simultaneous", name)
+}
+
+// plannedMove is the road a monster has chosen to take in a simultaneous step
+type plannedMove struct {
+	monster *Monster
+	move    *MoveOption
+}
+
+// roadKey identifies a road by its direction of travel
+type roadKey struct {
+	source, destination CityName
+	direction           string
+}
+
+// stepSimultaneously moves every active monster at once
+// Fast monsters make their extra moves in further rounds, in which only monsters at least that fast take part
+func (g *MonsterGame) stepSimultaneously() {
+	for round := 0; ; round++ {
+		var movers []*Monster
+		for _, monster := range g.ActiveMonsters.GetAll() {
+			if monster.Speed() > round {
+				movers = append(movers, monster)
+			}
+		}
+		if len(movers) == 0 {
+			return
+		}
+		g.moveSimultaneously(movers)
+	}
+}
+
+// moveSimultaneously has each monster choose a road while the others are still where they were, settles the fights
+// between monsters passing each other on a road, then moves the survivors and settles the fights in the cities they fill
+func (g *MonsterGame) moveSimultaneously(monsters []*Monster) {
+	var planned []plannedMove
+	for _, monster := range monsters {
+		move, err := g.chooseMove(monster)
+		if err != nil {
+			panic(err)
+		}
+		if move != nil {
+			planned = append(planned, plannedMove{monster, move})
+		}
+	}
+
+	planned = g.fightOnRoads(planned)
+
+	var arrivals []*City
+	arrived := make(map[CityName]bool)
+	for _, p := range planned {
+		if _, err := g.travel(p.monster, p.move.City); err != nil {
+			panic(err)
+		}
+		if !arrived[p.move.City.Name] {
+			arrived[p.move.City.Name] = true
+			arrivals = append(arrivals, p.move.City)
+		}
+	}
+	// Whether a city is full depends on who has left it as well as who has arrived, so it is only checked once everyone has moved
+	for _, city := range arrivals {
+		if city.Full() {
+			g.fight(city)
+		}
+	}
+}
+
+// fightOnRoads settles a fight on each road which monsters are travelling along in both directions,
+// returning the moves of the monsters which are still alive
+// Roads whose direction has no opposite can't be told apart from others between the same cities, so nobody meets on them
+func (g *MonsterGame) fightOnRoads(planned []plannedMove) []plannedMove {
+	travelling := make(map[roadKey][]*Monster)
+	for _, p := range planned {
+		key := roadKey{p.move.Road.Source, p.move.Road.Destination, p.move.Road.Direction}
+		travelling[key] = append(travelling[key], p.monster)
+	}
+
+	killed := make(map[MonsterID]bool)
+	settled := make(map[roadKey]bool)
+	for _, p := range planned {
+		road := p.move.Road
+		key := roadKey{road.Source, road.Destination, road.Direction}
+		opposite, ok := OppositeDirection(road.Direction)
+		if !ok || settled[key] {
+			continue
+		}
+		oncomingKey := roadKey{road.Destination, road.Source, opposite}
+		settled[key], settled[oncomingKey] = true, true
+		oncoming := travelling[oncomingKey]
+		if len(oncoming) == 0 {
+			continue
+		}
+
+		fighters := append(append([]*Monster(nil), travelling[key]...), oncoming...)
+		sort.Slice(fighters, func(i, j int) bool { return fighters[i].ID < fighters[j].ID })
+		outcome := g.combat.Fight(nil, fighters, g.rand)
+		if len(outcome.Killed) == 0 {
+			continue
+		}
+		for _, deadMonster := range outcome.Killed {
+			killed[deadMonster.ID] = true
+			// The dead never arrive, nor are they left in the city they set out from
+			g.world.GetCity(deadMonster.Location()).RemoveMonster(deadMonster)
+			g.ActiveMonsters.Remove(deadMonster)
+			deadMonster.SetStatus(StatusDead)
+		}
+		g.emit(RoadBattleFought{Step: g.steps, From: road.Source, To: road.Destination,
+			Winners: refsOf(outcome.Survivors), Monsters: refsOf(outcome.Killed)})
+	}
+
+	var survivors []plannedMove
+	for _, p := range planned {
+		if !killed[p.monster.ID] {
+			survivors = append(survivors, p)
+		}
+	}
+	return survivors
+}
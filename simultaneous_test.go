@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// simultaneousTestGame sets up a game with monsters placed in the given cities, in id order
+func simultaneousTestGame(t *testing.T, data string, mode StepMode, cities ...CityName) (*MonsterGame, *[]GameEvent) {
+	world, err := BuildWorldFromRecords(NewCSVReader(strings.NewReader(data)).ReadAll())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []GameEvent
+	game := NewMonsterGame(world, 10, 0, WithSeed(1), WithStepMode(mode), WithTermination(EndAfterIterations),
+		WithObserver(GameObserverFunc(func(e GameEvent) { events = append(events, e) })))
+	for i, city := range cities {
+		monster := NewMonsterWithName(uint(i), string(rune('A'+i))+"bc")
+		game.Monsters.Add(monster)
+		game.ActiveMonsters.Add(monster)
+		world.GetCity(city).Enter(monster)
+		monster.SetLocation(city)
+	}
+	return game, &events
+}
+
+func TestSimultaneousStepsIgnoreOrder(t *testing.T) {
+	// Abc follows Bbc along a one way road, they only meet if Abc arrives before Bbc has left
+	const chain = "Foo east=Bar\nBar east=Baz\nBaz\n"
+	game, _ := simultaneousTestGame(t, chain, SequentialSteps, "Foo", "Bar")
+	game.Step()
+	if !game.World().GetCity("Bar").Destroyed {
+		t.Error("Expected Abc to catch Bbc in Bar when monsters move one at a time")
+	}
+	game, _ = simultaneousTestGame(t, chain, SimultaneousSteps, "Foo", "Bar")
+	game.Step()
+	if game.World().GetCity("Bar").Destroyed || game.ActiveMonsters.Length() != 2 {
+		t.Error("Expected Bbc to have left Bar before Abc arrived when monsters move at once")
+	}
+
+	// Both monsters arrive in Bar together
+	game, events := simultaneousTestGame(t, "Foo east=Bar\nBaz west=Bar\nBar\n", SimultaneousSteps, "Foo", "Baz")
+	game.Step()
+	if !game.World().GetCity("Bar").Destroyed {
+		t.Fatal("Expected the monsters arriving in Bar at once to destroy it")
+	}
+	last := (*events)[len(*events)-1].(CityDestroyed)
+	if len(last.Monsters) != 2 {
+		t.Errorf("Expected both monsters to die in Bar, got %v", last.Monsters)
+	}
+}
+
+func TestSimultaneousStepsFightOnRoads(t *testing.T) {
+	game, events := simultaneousTestGame(t, "Foo north=Bar\nBar south=Foo\n", SimultaneousSteps, "Foo", "Bar")
+	game.Step()
+	if len(*events) != 1 {
+		t.Fatalf("Expected a single road battle, got %v", *events)
+	}
+	battle, ok := (*events)[0].(RoadBattleFought)
+	if !ok || battle.From != "Foo" || battle.To != "Bar" || len(battle.Monsters) != 2 {
+		t.Errorf("Expected Abc and Bbc to kill each other between Foo and Bar, got %+v", (*events)[0])
+	}
+	for _, city := range game.World().GetCities() {
+		if city.Destroyed || !city.Monsters.IsEmpty() {
+			t.Errorf("Expected monsters killed on a road to leave %s empty and standing", city.Name)
+		}
+	}
+	if game.ActiveMonsters.Length() != 0 {
+		t.Error("Expected no monsters to survive")
+	}
+}
+
+func TestParseStepMode(t *testing.T) {
+	if mode, err := ParseStepMode("simultaneous"); mode != SimultaneousSteps || err != nil {
+		t.Errorf("Expected simultaneous steps, got %v %v", mode, err)
+	}
+	if _, err := ParseStepMode("random"); err == nil {
+		t.Error("Expected an unknown step mode to be rejected")
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Species describes a kind of monster
+// Monsters without a species fight as the rules describe, move once a step and count once towards a city's capacity
+type Species struct {
+	Name   string `json:"name"`
+	Health int    `json:"health"` // Damage a monster can take before it dies, under combat rules which use it
+	Attack int    `json:"attack"` // Damage a monster deals in a fight, and its strength under the strength rule
+	Speed  int    `json:"speed"`  // Roads a monster travels along in each step
+	Weight int    `json:"weight"` // How much of a city's capacity a monster takes up
+}
+
+// SpawnGroup is a number of monsters of one species to create
+type SpawnGroup struct {
+	Species *Species
+	Count   uint
+}
+
+// SpawnMix says how many monsters of each species to create, monsters are given species in the order of the groups, by id
+type SpawnMix []SpawnGroup
+
+// Total returns the number of monsters in the mix
+func (mix SpawnMix) Total() uint {
+	var total uint
+	for _, group := range mix {
+		total += group.Count
+	}
+	return total
+}
+
+// speciesOf returns the species of the monster with the given id, nil for monsters beyond the end of the mix
+func (mix SpawnMix) speciesOf(id uint) *Species {
+	for _, group := range mix {
+		if id < group.Count {
+			return group.Species
+		}
+		id -= group.Count
+	}
+	return nil
+}
+
+// SpeciesConfig is a set of species and the mix of them to spawn, as read from a config file
+//
+//	{
+//	  "species": [
+//	    {"name": "giant", "health": 20, "attack": 5, "speed": 1, "weight": 2},
+//	    {"name": "imp", "health": 1, "attack": 1, "speed": 2, "weight": 1}
+//	  ],
+//	  "mix": [{"species": "giant", "count": 2}, {"species": "imp", "count": 50}]
+//	}
+//
+// Health, speed and weight default to 1 when left out, attack defaults to 0
+type SpeciesConfig struct {
+	Species []*Species
+	Mix     SpawnMix
+}
+
+// speciesConfigFile is the layout of a species config file
+type speciesConfigFile struct {
+	Species []*Species `json:"species"`
+	Mix     []struct {
+		Species string `json:"species"`
+		Count   uint   `json:"count"`
+	} `json:"mix"`
+}
+
+// ReadSpeciesConfig reads and checks a species config
+func ReadSpeciesConfig(r io.Reader) (*SpeciesConfig, error) {
+	var file speciesConfigFile
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, err
+	}
+	config := &SpeciesConfig{}
+	for _, species := range file.Species {
+		if species.Name == "" {
+			return nil, fmt.Errorf("Every species needs a name")
+		}
+		if config.Get(species.Name) != nil {
+			return nil, fmt.Errorf("Species %q is defined more than once", species.Name)
+		}
+		for _, attr := range []*int{&species.Health, &species.Speed, &species.Weight} {
+			if *attr == 0 {
+				*attr = 1
+			}
+		}
+		if species.Health < 1 || species.Attack < 0 || species.Speed < 1 || species.Weight < 1 {
+			return nil, fmt.Errorf("Species %q: health, speed and weight must be at least 1 and attack at least 0", species.Name)
+		}
+		config.Species = append(config.Species, species)
+	}
+	for _, group := range file.Mix {
+		species := config.Get(group.Species)
+		if species == nil {
+			return nil, fmt.Errorf("Unknown species %q in the mix", group.Species)
+		}
+		config.Mix = append(config.Mix, SpawnGroup{Species: species, Count: group.Count})
+	}
+	return config, nil
+}
+
+// LoadSpeciesConfig reads a species config file
+func LoadSpeciesConfig(path string) (*SpeciesConfig, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	config, err := ReadSpeciesConfig(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return config, nil
+}
+
+// Get returns the species with the given name, or nil if there isn't one
+func (c *SpeciesConfig) Get(name string) *Species {
+	for _, species := range c.Species {
+		if species.Name == name {
+			return species
+		}
+	}
+	return nil
+}
+
+// ParseSpawnMix converts a comma separated list of species counts, e.g. "giant=2,imp=50", to a mix of the config's species
+func (c *SpeciesConfig) ParseSpawnMix(spec string) (SpawnMix, error) {
+	var mix SpawnMix
+	for _, item := range strings.Split(spec, ",") {
+		parts := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%q: expected species=count", item)
+		}
+		species := c.Get(parts[0])
+		if species == nil {
+			return nil, fmt.Errorf("Unknown species %q", parts[0])
+		}
+		count, err := strconv.ParseUint(parts[1], 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("%q: count must be a whole number", item)
+		}
+		mix = append(mix, SpawnGroup{Species: species, Count: uint(count)})
+	}
+	return mix, nil
+}
+
+// loadSpawnMix reads the species config at path and picks the mix to spawn, spec overriding the config's own mix if given
+// The mix is nil if there is no config
+func loadSpawnMix(path, spec string) (SpawnMix, error) {
+	if path == "" {
+		if spec != "" {
+			return nil, fmt.Errorf("A spawn mix needs a species config")
+		}
+		return nil, nil
+	}
+	config, err := LoadSpeciesConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if spec != "" {
+		return config.ParseSpawnMix(spec)
+	}
+	return config.Mix, nil
+}
+
+// checkMonsterCount makes sure a number of monsters given along with a spawn mix is the size of the mix
+// Fewer monsters would leave part of the mix unspawned, and more would leave some of them without a species
+func checkMonsterCount(n uint, mix SpawnMix) error {
+	if mix != nil && n != mix.Total() {
+		return fmt.Errorf("-n %d doesn't match the %d monsters in the spawn mix", n, mix.Total())
+	}
+	return nil
+}
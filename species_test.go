@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const speciesTestConfig = `{
+  "species": [
+    {"name": "giant", "health": 20, "attack": 6, "weight": 2},
+    {"name": "imp", "attack": 1, "speed": 2}
+  ],
+  "mix": [{"species": "giant", "count": 1}, {"species": "imp", "count": 3}]
+}`
+
+func TestReadSpeciesConfig(t *testing.T) {
+	config, err := ReadSpeciesConfig(strings.NewReader(speciesTestConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	imp := config.Get("imp")
+	if imp == nil || imp.Health != 1 || imp.Speed != 2 || imp.Weight != 1 {
+		t.Errorf("Expected imps to default to health and weight 1, got %+v", imp)
+	}
+	if config.Mix.Total() != 4 || config.Mix.speciesOf(0) != config.Get("giant") || config.Mix.speciesOf(3) != imp || config.Mix.speciesOf(4) != nil {
+		t.Errorf("Unexpected mix %+v", config.Mix)
+	}
+
+	mix, err := config.ParseSpawnMix("imp=5, giant=2")
+	if err != nil || mix.Total() != 7 || mix.speciesOf(0) != imp {
+		t.Errorf("Expected 5 imps then 2 giants, got %+v %v", mix, err)
+	}
+	for _, spec := range []string{"dragon=1", "imp", "imp=many"} {
+		if _, err := config.ParseSpawnMix(spec); err == nil {
+			t.Errorf("Expected mix %q to be rejected", spec)
+		}
+	}
+
+	bad := []string{
+		`{"species": [{"health": 1}]}`,
+		`{"species": [{"name": "imp"}, {"name": "imp"}]}`,
+		`{"species": [{"name": "imp", "speed": -1}]}`,
+		`{"species": [{"name": "imp"}], "mix": [{"species": "dragon", "count": 1}]}`,
+		`{"species": [{"name": "imp", "colour": "red"}]}`,
+	}
+	for _, data := range bad {
+		if _, err := ReadSpeciesConfig(strings.NewReader(data)); err == nil {
+			t.Errorf("Expected config %s to be rejected", data)
+		}
+	}
+}
+
+func TestSpawnMixInGame(t *testing.T) {
+	config, _ := ReadSpeciesConfig(strings.NewReader(speciesTestConfig))
+	world, _ := BuildWorldFromRecords(NewCSVReader(strings.NewReader(strategyTestMap)).ReadAll())
+	game := NewMonsterGame(world, 1, config.Mix.Total(), WithSeed(1), WithSpawnMix(config.Mix), WithCombat(HealthCombat{}),
+		WithTermination(EndAfterIterations))
+	giant := game.Monsters.Get(0)
+	if giant.Species() != config.Get("giant") || giant.Weight() != 2 || giant.Strength() != 6 {
+		t.Errorf("Expected the first monster to be a giant, got %+v", giant.Species())
+	}
+	game.Start()
+	for _, monster := range game.Monsters.GetAll() {
+		if monster.Species().Name == "imp" && monster.Status() == StatusActive && monster.Moves() != 2 {
+			t.Errorf("Expected imps to move twice in a step, %s moved %d times", monster.Name(), monster.Moves())
+		}
+	}
+}
+
+func TestCheckMonsterCount(t *testing.T) {
+	config, _ := ReadSpeciesConfig(strings.NewReader(speciesTestConfig))
+	if err := checkMonsterCount(4, config.Mix); err != nil {
+		t.Errorf("Expected the size of the mix to be accepted, got %v", err)
+	}
+	for _, n := range []uint{3, 5} {
+		if err := checkMonsterCount(n, config.Mix); err == nil {
+			t.Errorf("Expected %d monsters to be rejected for a mix of 4", n)
+		}
+	}
+	if err := checkMonsterCount(3, nil); err != nil {
+		t.Errorf("Expected any number of monsters without a mix, got %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// seekRadius is the number of roads a seeking monster looks along for another monster
+const seekRadius = 8
+
+// MovementStrategy decides which road a monster takes next
+// Strategies keep any state they need on the monster, such as its last road, so that one strategy can be shared by many monsters
+type MovementStrategy interface {
+	// Choose returns the index of the move the monster makes, moves is never empty
+	// All randomness must be drawn from r so that seeded games can be replayed
+	Choose(monster *Monster, moves []MoveOption, world *World, r *rand.Rand) int
+}
+
+// movementStrategies are the built in strategies by the name used on the command line
+var movementStrategies = map[string]MovementStrategy{
+	"uniform":      UniformStrategy{},
+	"momentum":     MomentumStrategy{},
+	"no-backtrack": NoBacktrackStrategy{},
+	"seek":         SeekStrategy{},
+	"flee":         FleeStrategy{},
+	"hubs":         HubStrategy{},
+}
+
+// ParseStrategies converts a comma separated list of strategy names, e.g. "uniform,seek", to strategies
+func ParseStrategies(spec string) ([]MovementStrategy, error) {
+	var strategies []MovementStrategy
+	for _, name := range strings.Split(spec, ",") {
+		strategy, ok := movementStrategies[strings.TrimSpace(name)]
+		if !ok {
+			var names []string
+			for name := range movementStrategies {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("Unknown movement strategy %q, expected one of %s", name, strings.Join(names, ", "))
+		}
+		strategies = append(strategies, strategy)
+	}
+	return strategies, nil
+}
+
+// UniformStrategy picks any road with equal probability, as in the rules
+type UniformStrategy struct{}
+
+// Choose picks a move uniformly at random
+func (UniformStrategy) Choose(monster *Monster, moves []MoveOption, world *World, r *rand.Rand) int {
+	return r.Intn(len(moves))
+}
+
+// MomentumStrategy keeps heading in the same compass direction while it can, and picks at random when it can't
+type MomentumStrategy struct{}
+
+// Choose takes the road in the direction of the monster's last road if there is one
+func (MomentumStrategy) Choose(monster *Monster, moves []MoveOption, world *World, r *rand.Rand) int {
+	if last := monster.LastRoad(); last != nil {
+		for i, move := range moves {
+			if move.Road.Direction == last.Direction {
+				return i
+			}
+		}
+	}
+	return r.Intn(len(moves))
+}
+
+// NoBacktrackStrategy picks at random from the roads which don't lead straight back to where the monster came from
+// A monster at a dead end turns back
+type NoBacktrackStrategy struct{}
+
+// Choose picks a move at random, avoiding the city the monster has just left
+func (NoBacktrackStrategy) Choose(monster *Monster, moves []MoveOption, world *World, r *rand.Rand) int {
+	last := monster.LastRoad()
+	if last == nil {
+		return r.Intn(len(moves))
+	}
+	var forward []int
+	for i, move := range moves {
+		if move.City.Name != last.Source {
+			forward = append(forward, i)
+		}
+	}
+	if len(forward) == 0 {
+		return r.Intn(len(moves))
+	}
+	return forward[r.Intn(len(forward))]
+}
+
+// SeekStrategy heads along the shortest path to the nearest other monster within seekRadius roads
+// Monsters with nobody in sight wander at random
+type SeekStrategy struct{}
+
+// Choose takes the first road of the shortest path to another monster, preferring earlier roads when paths are as short
+func (SeekStrategy) Choose(monster *Monster, moves []MoveOption, world *World, r *rand.Rand) int {
+	type visit struct {
+		city  *City
+		first int // Index of the move the path starts with
+	}
+	seen := map[CityName]bool{monster.Location(): true}
+	var queue []visit
+	for i, move := range moves {
+		if !seen[move.City.Name] {
+			seen[move.City.Name] = true
+			queue = append(queue, visit{move.City, i})
+		}
+	}
+	for depth := 1; depth <= seekRadius && len(queue) > 0; depth++ {
+		var next []visit
+		for _, v := range queue {
+			if !v.city.Monsters.IsEmpty() {
+				return v.first
+			}
+			onward, err := world.FindPossibleMoves(v.city.Name)
+			if err != nil {
+				continue
+			}
+			for _, move := range onward {
+				if !seen[move.City.Name] {
+					seen[move.City.Name] = true
+					next = append(next, visit{move.City, v.first})
+				}
+			}
+		}
+		queue = next
+	}
+	return r.Intn(len(moves))
+}
+
+// FleeStrategy heads for the least crowded city, counting the monsters in it and in the cities next to it
+// Ties are broken at random
+type FleeStrategy struct{}
+
+// Choose picks a move at random from those leading to the least crowded cities
+func (FleeStrategy) Choose(monster *Monster, moves []MoveOption, world *World, r *rand.Rand) int {
+	crowding := make([]int, len(moves))
+	for i, move := range moves {
+		crowding[i] = move.City.Monsters.Length()
+		neighbours, err := world.FindPossibleMoves(move.City.Name)
+		if err != nil {
+			continue
+		}
+		for _, n := range neighbours {
+			crowding[i] += n.City.Monsters.Length()
+			// The monster won't be where it is now once it has moved
+			if n.City.Monsters.Contains(monster.ID) {
+				crowding[i]--
+			}
+		}
+	}
+	var quietest []int
+	for i := range moves {
+		switch {
+		case len(quietest) == 0 || crowding[i] < crowding[quietest[0]]:
+			quietest = []int{i}
+		case crowding[i] == crowding[quietest[0]]:
+			quietest = append(quietest, i)
+		}
+	}
+	return quietest[r.Intn(len(quietest))]
+}
+
+// HubStrategy prefers well connected cities, picking a road with probability in proportion to the number of roads
+// out of the city it leads to, plus one so that dead ends can still be visited
+type HubStrategy struct{}
+
+// Choose picks a move at random, weighted by the number of roads out of its destination
+func (HubStrategy) Choose(monster *Monster, moves []MoveOption, world *World, r *rand.Rand) int {
+	weights := make([]int, len(moves))
+	total := 0
+	for i, move := range moves {
+		onward, _ := world.FindPossibleMoves(move.City.Name)
+		weights[i] = len(onward) + 1
+		total += weights[i]
+	}
+	pick := r.Intn(total)
+	for i, weight := range weights {
+		if pick < weight {
+			return i
+		}
+		pick -= weight
+	}
+	return len(moves) - 1
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// strategyTestMap is a cross with Centre in the middle, and a second monster two roads to the east
+const strategyTestMap = "Centre north=N south=S east=E west=W\nN south=Centre\nS north=Centre\nE west=Centre east=Far\nW east=Centre\nFar west=E\n"
+
+func strategyTestWorld(t *testing.T) (*World, *Monster) {
+	world, err := BuildWorldFromRecords(NewCSVReader(strings.NewReader(strategyTestMap)).ReadAll())
+	if err != nil {
+		t.Fatal(err)
+	}
+	monster := NewMonsterWithName(0, "Abc")
+	world.GetCity("Centre").AddMonster(monster)
+	monster.SetLocation("Centre")
+	other := NewMonsterWithName(1, "Def")
+	world.GetCity("Far").AddMonster(other)
+	other.SetLocation("Far")
+	return world, monster
+}
+
+func TestMovementStrategies(t *testing.T) {
+	tests := []struct {
+		strategy MovementStrategy
+		lastRoad *Road
+		expected CityName
+	}{
+		{MomentumStrategy{}, NewRoad(South, "N", "Centre"), "S"},
+		{SeekStrategy{}, nil, "E"},
+	}
+	for _, test := range tests {
+		world, monster := strategyTestWorld(t)
+		monster.SetLastRoad(test.lastRoad)
+		moves, _ := world.FindPossibleMoves("Centre")
+		for seed := int64(0); seed < 10; seed++ {
+			choice := moves[test.strategy.Choose(monster, moves, world, rand.New(rand.NewSource(seed)))]
+			if choice.City.Name != test.expected {
+				t.Errorf("Expected %T to go to %s, got %s", test.strategy, test.expected, choice.City.Name)
+			}
+		}
+	}
+}
+
+func TestNoBacktrackAndFleeStrategies(t *testing.T) {
+	world, monster := strategyTestWorld(t)
+	monster.SetLastRoad(NewRoad(West, "E", "Centre"))
+	moves, _ := world.FindPossibleMoves("Centre")
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		if city := moves[NoBacktrackStrategy{}.Choose(monster, moves, world, r)].City.Name; city == "E" {
+			t.Fatal("Expected no-backtrack never to return to E")
+		}
+		if city := moves[FleeStrategy{}.Choose(monster, moves, world, r)].City.Name; city == "E" {
+			t.Fatal("Expected flee never to head towards the monster in Far")
+		}
+	}
+}
+
+func TestUniformStrategyKeepsSeededGames(t *testing.T) {
+	play := func(opts ...GameOption) string {
+		world, _ := BuildWorldFromRecords(NewCSVReader(strings.NewReader(strategyTestMap)).ReadAll())
+		var out strings.Builder
+		game := NewMonsterGame(world, 50, 3, append(opts, WithSeed(7), WithObserver(NewEventLogWriter(&out)))...)
+		game.Start()
+		return out.String()
+	}
+	if play() != play(WithStrategies(UniformStrategy{})) {
+		t.Error("Expected the uniform strategy to play out exactly like the default")
+	}
+}
+
+func TestParseStrategies(t *testing.T) {
+	strategies, err := ParseStrategies("seek, hubs")
+	if err != nil || len(strategies) != 2 {
+		t.Errorf("Expected 2 strategies, got %v %v", strategies, err)
+	}
+	if _, err := ParseStrategies("teleport"); err == nil {
+		t.Error("Expected an unknown strategy to be rejected")
+	}
+}
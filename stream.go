@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// spectatorBuffer is the number of events a spectator may fall behind by before it starts missing them
+// A spectator which has missed events is sent a fresh snapshot once it has caught up with the rest
+const spectatorBuffer = 4096
+
+// streamedEvent is a game event sent to spectators
+// Message holds what the text output of the game says about the event, so that the stream and the text never disagree
+type streamedEvent struct {
+	*eventRecord
+	Message string `json:"message,omitempty"`
+}
+
+// gameSnapshot is the state of a game sent to spectators when they join
+type gameSnapshot struct {
+	Game     gameSummary      `json:"game"`
+	Cities   []citySummary    `json:"cities"`
+	Monsters []monsterSummary `json:"monsters"`
+	History  []streamedEvent  `json:"history"`
+}
+
+// spectator is a client watching the events of a game as they happen
+type spectator struct {
+	events chan streamedEvent
+	behind bool // Whether events were dropped because the buffer was full, guarded by the session's lock
+}
+
+// newStreamedEvent converts a game event for spectators, describing it as the TextObserver would
+func newStreamedEvent(event GameEvent) (streamedEvent, error) {
+	rec, err := newEventRecord(event)
+	if err != nil {
+		return streamedEvent{}, err
+	}
+	var text bytes.Buffer
+	NewTextObserver(&text).OnEvent(event)
+	return streamedEvent{eventRecord: rec, Message: string(bytes.TrimSpace(text.Bytes()))}, nil
+}
+
+// broadcast sends an event to every spectator of a game, the session must be locked
+// Spectators which have fallen too far behind miss the event rather than holding up the game
+// They miss every event until they catch up, so that none is sent out of order with the snapshot they are sent then
+func (s *gameSession) broadcast(event GameEvent) {
+	if len(s.spectators) == 0 {
+		return
+	}
+	streamed, err := newStreamedEvent(event)
+	if err != nil {
+		return
+	}
+	for spec := range s.spectators {
+		if spec.behind {
+			continue
+		}
+		select {
+		case spec.events <- streamed:
+		default:
+			spec.behind = true
+		}
+	}
+}
+
+// watch adds a spectator to a game, the session must be locked
+func (s *gameSession) watch() *spectator {
+	spec := &spectator{events: make(chan streamedEvent, spectatorBuffer)}
+	s.spectators[spec] = struct{}{}
+	return spec
+}
+
+// unwatch removes a spectator from a game, closing its events, the session must be locked
+func (s *gameSession) unwatch(spec *spectator) {
+	if _, ok := s.spectators[spec]; ok {
+		delete(s.spectators, spec)
+		close(spec.events)
+	}
+}
+
+// unwatchAll removes every spectator from a game, ending their streams, the session must be locked
+func (s *gameSession) unwatchAll() {
+	for spec := range s.spectators {
+		s.unwatch(spec)
+	}
+}
+
+// catchUp brings a spectator which has missed events up to date, the session must be locked
+// The events still waiting are dropped as a fresh snapshot covers them, followed by the end of the game if it is over,
+// as a spectator must always be told how the game ended
+func (s *gameSession) catchUp(spec *spectator) (gameSnapshot, []streamedEvent) {
+	for drained := false; !drained; {
+		select {
+		case _, ok := <-spec.events:
+			drained = !ok
+		default:
+			drained = true
+		}
+	}
+	spec.behind = false
+	var missed []streamedEvent
+	if s.ended != nil {
+		if ended, err := newStreamedEvent(*s.ended); err == nil {
+			missed = append(missed, ended)
+		}
+	}
+	return s.snapshot(), missed
+}
+
+// snapshot describes the current state of a game for a spectator who has just joined, the session must be locked
+func (s *gameSession) snapshot() gameSnapshot {
+	snapshot := gameSnapshot{
+		Game:     s.summary(),
+		Cities:   citySummaries(s.game.World()),
+		Monsters: monsterSummaries(s.game.Monsters),
+		History:  make([]streamedEvent, len(s.history)),
+	}
+	for i, event := range s.history {
+		snapshot.History[i], _ = newStreamedEvent(event)
+	}
+	return snapshot
+}
+
+// streamEvents sends the events of a game to the client as Server-Sent Events until it disconnects
+// The first event is a snapshot of the game, followed by each move and destruction as it happens
+// A client which falls too far behind is sent another snapshot in place of the events it missed
+// The session is locked by streamEvents itself, as the stream lasts while the game is being played
+func streamEvents(w http.ResponseWriter, r *http.Request, session *gameSession) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeHTTPError(w, http.StatusInternalServerError, fmt.Errorf("Streaming is not supported by this connection"))
+		return
+	}
+
+	// The snapshot is taken as the spectator joins so that no event is missed or seen twice
+	session.mu.Lock()
+	snapshot := session.snapshot()
+	spec := session.watch()
+	session.mu.Unlock()
+	defer func() {
+		session.mu.Lock()
+		session.unwatch(spec)
+		session.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := writeServerSentEvent(w, "snapshot", snapshot); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-spec.events:
+			if !ok {
+				return
+			}
+			if err := writeServerSentEvent(w, event.Type, event); err != nil {
+				return
+			}
+			// Send whatever else is already waiting before flushing, so that fast games don't flush every event
+			for more := true; more; {
+				select {
+				case event, ok := <-spec.events:
+					if !ok {
+						flusher.Flush()
+						return
+					}
+					if err := writeServerSentEvent(w, event.Type, event); err != nil {
+						return
+					}
+				default:
+					more = false
+				}
+			}
+			session.mu.Lock()
+			behind := spec.behind
+			var missed []streamedEvent
+			if behind {
+				snapshot, missed = session.catchUp(spec)
+			}
+			session.mu.Unlock()
+			if behind {
+				if err := writeServerSentEvent(w, "snapshot", snapshot); err != nil {
+					return
+				}
+				for _, event := range missed {
+					if err := writeServerSentEvent(w, event.Type, event); err != nil {
+						return
+					}
+				}
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeServerSentEvent writes one event of an event stream, e.g. "event: destroyed\ndata: {...}\n\n"
+func writeServerSentEvent(w http.ResponseWriter, name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+	return err
+}
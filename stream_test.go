@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamEvents(t *testing.T) {
+	server := httptest.NewServer(NewGameServer())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/games?n=2&seed=1", "text/plain", strings.NewReader(serverTestMap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	stream, err := http.Get(server.URL + "/games/1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Body.Close()
+	if ct := stream.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %q", ct)
+	}
+	scanner := bufio.NewScanner(stream.Body)
+	next := func() (string, []byte) { return nextServerSentEvent(t, scanner) }
+
+	name, data := next()
+	var snapshot gameSnapshot
+	if err := json.Unmarshal(data, &snapshot); name != "snapshot" || err != nil {
+		t.Fatalf("Expected a snapshot first, got %s %s", name, data)
+	}
+	if len(snapshot.Cities) != 3 || len(snapshot.Monsters) != 2 {
+		t.Errorf("Expected a snapshot of 3 cities and 2 monsters, got %+v", snapshot)
+	}
+
+	resp, err = http.Post(server.URL+"/games/1/run", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	for {
+		name, data := next()
+		var event struct {
+			eventRecord
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(data, &event); err != nil || event.Type != name {
+			t.Fatalf("Unexpected %s event %s", name, data)
+		}
+		if name == eventTypeDestroyed {
+			expected := FormatCityDestroyed(CityDestroyed{Step: event.Step, City: event.City, Monsters: event.Monsters})
+			if event.Message != expected {
+				t.Errorf("Expected message %q, got %q", expected, event.Message)
+			}
+		}
+		if name == eventTypeEnded {
+			break
+		}
+	}
+}
+
+// nextServerSentEvent reads the name and data of the next event in a stream
+func nextServerSentEvent(t *testing.T, scanner *bufio.Scanner) (string, []byte) {
+	var name string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			return name, []byte(strings.TrimPrefix(line, "data: "))
+		}
+	}
+	t.Fatalf("Stream ended early: %v", scanner.Err())
+	return "", nil
+}
+
+func TestSlowSpectatorCatchesUp(t *testing.T) {
+	server := httptest.NewServer(NewGameServer())
+	defer server.Close()
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	// A lone monster never fights, so it moves in every one of the game's steps
+	resp, err := client.Post(server.URL+"/games?n=1&seed=3&max=50000&termination=iterations", "text/plain", strings.NewReader(serverTestMap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	stream, err := client.Get(server.URL + "/games/1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Body.Close()
+	scanner := bufio.NewScanner(stream.Body)
+	if name, _ := nextServerSentEvent(t, scanner); name != "snapshot" {
+		t.Fatalf("Expected a snapshot first, got %s", name)
+	}
+
+	// Nothing is read from the stream until the run is over, far more events than the spectator's buffer holds
+	if resp, err = client.Post(server.URL+"/games/1/run", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	var finished gameSummary
+	err = json.NewDecoder(resp.Body).Decode(&finished)
+	resp.Body.Close()
+	if err != nil || finished.Steps <= spectatorBuffer {
+		t.Fatalf("Expected a run of more than %d steps, got %+v %v", spectatorBuffer, finished, err)
+	}
+
+	moved, snapshots := 0, 0
+	for {
+		name, data := nextServerSentEvent(t, scanner)
+		if name == "snapshot" {
+			var snapshot gameSnapshot
+			if err := json.Unmarshal(data, &snapshot); err != nil || snapshot.Game.Steps == 0 {
+				t.Errorf("Expected a snapshot of the game under way, got %s", data)
+			}
+			snapshots++
+		}
+		if name == eventTypeMoved {
+			moved++
+		}
+		if name == eventTypeEnded {
+			break
+		}
+	}
+	// Every move is either streamed or covered by a later snapshot
+	if moved > finished.Steps || (moved < finished.Steps && snapshots == 0) {
+		t.Errorf("Expected a snapshot in place of any missed moves, got %d snapshots and %d of %d moves", snapshots, moved, finished.Steps)
+	}
+}
+
+func TestDeleteGameEndsStreams(t *testing.T) {
+	server := httptest.NewServer(NewGameServer())
+	defer server.Close()
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	resp, err := client.Post(server.URL+"/games?n=2&seed=1", "text/plain", strings.NewReader(serverTestMap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	stream, err := client.Get(server.URL + "/games/1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Body.Close()
+	scanner := bufio.NewScanner(stream.Body)
+	// Wait for the snapshot, so that the spectator is watching before the game is deleted
+	for scanner.Scan() && !strings.HasPrefix(scanner.Text(), "data: ") {
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/games/1", nil)
+	if resp, err = client.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	for scanner.Scan() {
+	}
+	if err := scanner.Err(); err != nil {
+		t.Errorf("Expected the stream to end when the game is deleted, got %v", err)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// SweepPoint is the outcome of a batch of games for one combination of the swept parameters
+type SweepPoint struct {
+	Monsters uint `json:"monsters"`
+	Capacity int  `json:"capacity"` // Capacity of cities without a capacity attribute, -1 for unlimited
+	Runs     int  `json:"runs"`
+	// Fraction of the map's cities destroyed by the end of the game
+	DestroyedFraction Distribution `json:"destroyed_fraction"`
+	// Fraction of the map's cities destroyed while the monsters were being placed, before anyone moved
+	SpawnDestroyedFraction Distribution `json:"spawn_destroyed_fraction"`
+	// Steps until every monster was dead or trapped, only counting the games where that happened
+	StepsToSettle Distribution `json:"steps_to_settle"`
+	SettledRuns   int          `json:"settled_runs"`
+	// Cities in the largest connected group left standing
+	LargestRegion Distribution `json:"largest_region"`
+}
+
+// SweepConfig describes a parameter sweep, a batch of games is played for each monster count and capacity
+type SweepConfig struct {
+	Batch      BatchConfig // Runs, seed and limits of each batch, its Monsters are ignored
+	Monsters   []uint
+	Capacities []int // Default city capacities to try, the map's own when empty
+}
+
+// Sweep plays a batch of games for every combination of monster count and capacity
+// Every batch uses the same seed, so that differences between points come from the parameters rather than the luck of the draw
+func Sweep(w *World, config SweepConfig) []SweepPoint {
+	capacities := config.Capacities
+	if len(capacities) == 0 {
+		capacities = []int{defaultCityCapacity}
+	}
+	total := float64(len(w.GetCities()))
+
+	var points []SweepPoint
+	for _, capacity := range capacities {
+		world := w
+		if len(config.Capacities) > 0 {
+			world = w.Copy()
+			world.SetDefaultCapacity(capacity)
+		}
+		for _, monsters := range config.Monsters {
+			batch := config.Batch
+			batch.Monsters = monsters
+			report := PlayBatch(world, batch)
+
+			point := SweepPoint{Monsters: monsters, Capacity: capacity, Runs: batch.Runs}
+			var destroyed, spawnDestroyed, steps, regions []float64
+			for _, result := range report.Results {
+				destroyed = append(destroyed, float64(result.CitiesDestroyed)/total)
+				spawnDestroyed = append(spawnDestroyed, float64(result.SpawnDestroyed)/total)
+				regions = append(regions, float64(result.LargestRegion))
+				if result.Reason == EndNoActiveMonsters || result.Reason == EndAllCitiesDestroyed {
+					steps = append(steps, float64(result.Steps))
+				}
+			}
+			point.DestroyedFraction = NewDistribution(destroyed)
+			point.SpawnDestroyedFraction = NewDistribution(spawnDestroyed)
+			point.StepsToSettle = NewDistribution(steps)
+			point.SettledRuns = len(steps)
+			point.LargestRegion = NewDistribution(regions)
+			points = append(points, point)
+		}
+	}
+	return points
+}
+
+// WriteSweepCSV writes a sweep as a tidy table, with a row for each measure at each point
+func WriteSweepCSV(w io.Writer, points []SweepPoint) error {
+	out := csv.NewWriter(w)
+	out.Write(append([]string{"monsters", "capacity", "measure", "runs"}, distributionHeader...))
+	for _, point := range points {
+		capacity := strconv.Itoa(point.Capacity)
+		if point.Capacity < 0 {
+			capacity = unlimitedCapacity
+		}
+		measures := []struct {
+			name         string
+			runs         int
+			distribution Distribution
+		}{
+			{"destroyed_fraction", point.Runs, point.DestroyedFraction},
+			{"spawn_destroyed_fraction", point.Runs, point.SpawnDestroyedFraction},
+			{"steps_to_settle", point.SettledRuns, point.StepsToSettle},
+			{"largest_region", point.Runs, point.LargestRegion},
+		}
+		for _, m := range measures {
+			row := []string{strconv.FormatUint(uint64(point.Monsters), 10), capacity, m.name, strconv.Itoa(m.runs)}
+			out.Write(append(row, m.distribution.columns()...))
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// parseSweepValues reads a comma separated list of values and ranges, e.g. "10,20,50:100:25" is 10, 20, 50, 75 and 100
+// Ranges are start:end or start:end:step and include end if the steps land on it, parse converts single values
+func parseSweepValues(spec string, parse func(string) (int, error)) ([]int, error) {
+	var values []int
+	for _, item := range strings.Split(spec, ",") {
+		bounds := strings.Split(strings.TrimSpace(item), ":")
+		if len(bounds) == 1 {
+			v, err := parse(bounds[0])
+			if err != nil {
+				return nil, fmt.Errorf("%q: %v", item, err)
+			}
+			values = append(values, v)
+			continue
+		}
+		if len(bounds) > 3 {
+			return nil, fmt.Errorf("%q: expected start:end or start:end:step", item)
+		}
+		nums := []int{0, 0, 1}
+		for i, bound := range bounds {
+			n, err := strconv.Atoi(bound)
+			if err != nil {
+				return nil, fmt.Errorf("%q: expected start:end or start:end:step", item)
+			}
+			nums[i] = n
+		}
+		if nums[2] < 1 || nums[1] < nums[0] {
+			return nil, fmt.Errorf("%q: expected a positive step and an end after the start", item)
+		}
+		for v := nums[0]; v <= nums[1]; v += nums[2] {
+			if _, err := parse(strconv.Itoa(v)); err != nil {
+				return nil, fmt.Errorf("%q: %v", item, err)
+			}
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+// parseMonsterCount converts a monster count for a sweep
+func parseMonsterCount(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("number of monsters must be a whole number greater than 0")
+	}
+	return n, nil
+}
+
+// runSweep is the sweep command, which plays batches of games over ranges of monster counts and city capacities
+func runSweep(args []string) error {
+	flags := flag.NewFlagSet("sweep", flag.ExitOnError)
+	mapDataFn := flags.String("d", defaultMapDataFn, "input file path containing the map to play on")
+	inputFormat := flags.String("if", "", "format of the map data file, \"csv\" or \"json\" (default: guessed from the file extension)")
+	parseMode := flags.String("parse", "permissive", "how to treat problems in the map: \"permissive\", \"strict\" or \"lenient\"")
+	monsterSpec := flags.String("n", "", "numbers of monsters to try, a comma separated list of values and start:end:step ranges, e.g. 10:100:10")
+	capacitySpec := flags.String("capacity", "", "capacities to try for cities without a capacity attribute, values, ranges or \"inf\" (default: the map's own)")
+	runs := flags.Int("runs", 100, "number of games to play for each combination")
+	seed := flags.Int64("seed", 0, "seed from which the seed of each game is derived (default: time based)")
+	termination := flags.String("termination", "moves", terminationUsage)
+	strategy := flags.String("strategy", "uniform", strategyUsage)
+	combat := flags.String("combat", "annihilation", combatUsage)
+	stepMode := flags.String("step-mode", "sequential", stepModeUsage)
+	speciesFn := flags.String("species", "", speciesUsage)
+	mixSpec := flags.String("mix", "", mixUsage)
+	workers := flags.Int("workers", runtime.NumCPU(), "number of games to play at once")
+	outputFn := flags.String("o", "", "output file path to write the table to, writes to stdout as default")
+	outputFormat := flags.String("of", "", "format of the table, \"csv\" or \"json\" (default: guessed from the output file extension)")
+	flags.Parse(args)
+
+	if *monsterSpec == "" || *runs < 1 {
+		flags.Usage()
+		return nil
+	}
+	config := SweepConfig{Batch: BatchConfig{Runs: *runs, Seed: *seed, MaxIterations: 10000, Workers: *workers}}
+	counts, err := parseSweepValues(*monsterSpec, parseMonsterCount)
+	if err != nil {
+		return fmt.Errorf("-n %v", err)
+	}
+	for _, n := range counts {
+		config.Monsters = append(config.Monsters, uint(n))
+	}
+	if *capacitySpec != "" {
+		if config.Capacities, err = parseSweepValues(*capacitySpec, ParseCapacity); err != nil {
+			return fmt.Errorf("-capacity %v", err)
+		}
+	}
+	if config.Batch.Termination, err = ParseTermination(*termination); err != nil {
+		return err
+	}
+	if config.Batch.Strategies, err = ParseStrategies(*strategy); err != nil {
+		return err
+	}
+	if config.Batch.Combat, err = ParseCombatRule(*combat); err != nil {
+		return err
+	}
+	if config.Batch.StepMode, err = ParseStepMode(*stepMode); err != nil {
+		return err
+	}
+	// The first monsters of every game are given species from the spawn mix, any more have none
+	if config.Batch.Mix, err = loadSpawnMix(*speciesFn, *mixSpec); err != nil {
+		return err
+	}
+	for _, n := range config.Monsters {
+		if err := checkMonsterCount(n, config.Batch.Mix); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", err)
+		}
+	}
+	format := *outputFormat
+	if format == "" {
+		format = FormatFromPath(*outputFn)
+	}
+	if format != "csv" && format != "json" {
+		return unknownFormatError("table", format, []string{"csv", "json"})
+	}
+
+	mode, err := ParseLoadMode(*parseMode)
+	if err != nil {
+		return err
+	}
+	world, err := loadWorld(*mapDataFn, *inputFormat, mode)
+	if err != nil {
+		return err
+	}
+	config.Batch.Seed = resolveSeed(flags, config.Batch.Seed)
+
+	points := Sweep(world, config)
+
+	var target io.Writer = os.Stdout
+	if *outputFn != "" {
+		file, err := os.Create(*outputFn)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		target = file
+	}
+	if format == "json" {
+		encoder := json.NewEncoder(target)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(points)
+	}
+	return WriteSweepCSV(target, points)
+}
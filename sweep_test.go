@@ -0,0 +1,47 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSweepValues(t *testing.T) {
+	values, err := parseSweepValues("10,20,50:100:25,3:4", parseMonsterCount)
+	if expected := []int{10, 20, 50, 75, 100, 3, 4}; err != nil || !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected %v, got %v %v", expected, values, err)
+	}
+	values, err = parseSweepValues("2,inf", ParseCapacity)
+	if expected := []int{2, -1}; err != nil || !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected %v, got %v %v", expected, values, err)
+	}
+	for _, spec := range []string{"0", "5:1", "1:5:0", "1:2:3:4", "a"} {
+		if _, err := parseSweepValues(spec, parseMonsterCount); err == nil {
+			t.Errorf("Expected %q to be rejected", spec)
+		}
+	}
+}
+
+func TestSweep(t *testing.T) {
+	world, _ := BuildWorldFromRecords(NewCSVReader(strings.NewReader(serverTestMap)).ReadAll())
+	config := SweepConfig{
+		Batch:      BatchConfig{Runs: 10, Seed: 1, MaxIterations: 100, Workers: 2},
+		Monsters:   []uint{1, 2},
+		Capacities: []int{1, 2},
+	}
+	points := Sweep(world, config)
+	if len(points) != 4 {
+		t.Fatalf("Expected a point for each combination, got %d", len(points))
+	}
+	// A single monster destroys a city of capacity 1 as soon as it is placed
+	if first := points[0]; first.Monsters != 1 || first.Capacity != 1 || first.SpawnDestroyedFraction.Median != 1.0/3 {
+		t.Errorf("Expected the lone monster to destroy its city, got %+v", first)
+	}
+	// Nothing is destroyed by a single monster when it takes two
+	if third := points[2]; third.Capacity != 2 || third.DestroyedFraction.Max != 0 || third.LargestRegion.Min != 3 {
+		t.Errorf("Expected the lone monster to leave the map standing, got %+v", third)
+	}
+	if world.GetCity("Foo").Capacity() != 2 {
+		t.Error("Expected the sweep to leave the world's capacities alone")
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ANSI escape sequences used to draw the terminal visualisation
+const (
+	ansiClearScreen = "\x1b[H\x1b[2J"
+	ansiHideCursor  = "\x1b[?25l"
+	ansiShowCursor  = "\x1b[?25h"
+)
+
+const (
+	// tuiLogLines is the number of recent destruction messages shown
+	tuiLogLines = 6
+	// tuiMinDelay and tuiMaxDelay bound the time between steps while the game is running
+	tuiMinDelay = 10 * time.Millisecond
+	tuiMaxDelay = 2 * time.Second
+	// tuiScrollStep is the number of grid cells the view moves per key press
+	tuiScrollStep = 4
+)
+
+// Keys understood by the terminal visualisation, arrow keys are read as their escape sequences
+const (
+	tuiKeyQuit   = 'q'
+	tuiKeyPause  = ' '
+	tuiKeyStep   = 'n'
+	tuiKeyFaster = '+'
+	tuiKeySlower = '-'
+	tuiKeyUp     = "\x1b[A"
+	tuiKeyDown   = "\x1b[B"
+	tuiKeyRight  = "\x1b[C"
+	tuiKeyLeft   = "\x1b[D"
+)
+
+// TUI redraws a running game in the terminal after every step
+// It is a GameObserver so that it can keep a log of destroyed cities, and should be subscribed when the game is created
+type TUI struct {
+	game      *MonsterGame // The game being shown, set by Run
+	world     *World
+	layout    *Layout
+	style     GridStyle
+	log       []string      // Most recent destruction messages, oldest first
+	ended     string        // Why the game ended, empty while it is running
+	delay     time.Duration // Time between steps while running
+	paused    bool
+	viewX     int // Column of the first grid cell shown
+	viewY     int // Row of the first grid cell shown
+	rows      int // Size of the terminal
+	cols      int
+	terminal  io.Writer
+	afterStep func(game *MonsterGame) error // Called after every step played, e.g. to save the game, an error stops the visualisation
+	err       error                         // Error returned by afterStep
+}
+
+// OnEvent records destruction and battle messages and the end of the game
+func (t *TUI) OnEvent(event GameEvent) {
+	switch e := event.(type) {
+	case CityDestroyed:
+		t.addLog(e.Step, FormatCityDestroyed(e))
+	case BattleFought:
+		t.addLog(e.Step, FormatBattle(e))
+	case RoadBattleFought:
+		t.addLog(e.Step, FormatRoadBattle(e))
+	case GameEnded:
+		t.ended = string(e.Reason)
+	}
+}
+
+// addLog adds a message to the log, dropping the oldest once it is full
+func (t *TUI) addLog(step int, msg string) {
+	t.log = append(t.log, fmt.Sprintf("[%d] %s", step, msg))
+	if len(t.log) > tuiLogLines {
+		t.log = t.log[len(t.log)-tuiLogLines:]
+	}
+}
+
+// Frame draws the whole screen: a status line, the part of the grid which fits, the monsters and the log
+func (t *TUI) Frame() string {
+	var frame bytes.Buffer
+
+	state := "running"
+	switch {
+	case t.ended != "":
+		state = "finished: " + t.ended
+	case t.paused:
+		state = "paused"
+	}
+	destroyed := 0
+	for _, city := range t.world.GetCities() {
+		if city.Destroyed {
+			destroyed++
+		}
+	}
+	fmt.Fprintf(&frame, "Step %d  monsters %d active  cities %d destroyed  delay %v  [%s]\n",
+		t.game.Steps(), t.game.ActiveMonsters.Length(), destroyed, t.delay, state)
+	frame.WriteString("space pause/resume  n step  +/- speed  arrows scroll  q quit\n\n")
+
+	// Leave room for the header, monsters and log below the grid
+	gridRows := t.rows - 3 - 1 - 2 - tuiLogLines - 2
+	gridLines := strings.Split(strings.TrimRight(RenderGrid(t.world, t.layout, t.style), "\n"), "\n")
+	// Each grid row is drawn as a line of cells followed by a line of roads leading south
+	top := minInt(t.viewY*2, len(gridLines))
+	left := t.viewX * (gridCellWidth + 1)
+	for i := top; i < len(gridLines) && i < top+maxInt(gridRows, 1); i++ {
+		frame.WriteString(cropColumns(gridLines[i], left, t.cols) + "\n")
+	}
+
+	frame.WriteString("\n" + cropColumns(t.monsterSummary(), 0, t.cols) + "\n\n")
+	for _, msg := range t.log {
+		frame.WriteString(cropColumns(msg, 0, t.cols) + "\n")
+	}
+	return frame.String()
+}
+
+// monsterSummary lists the active monsters with their locations and grid positions
+func (t *TUI) monsterSummary() string {
+	monsters := t.game.ActiveMonsters.GetAll()
+	if len(monsters) == 0 {
+		return "No active monsters"
+	}
+	entries := make([]string, len(monsters))
+	for i, monster := range monsters {
+		pos := t.layout.Positions[monster.Location()]
+		entries[i] = fmt.Sprintf("%s@%s(%d,%d)", monster.Name(), monster.Location(), pos.X, pos.Y)
+	}
+	sort.Strings(entries)
+	return "Monsters: " + strings.Join(entries, " ")
+}
+
+// cropColumns cuts a line down to the columns from left which fit in width, counting characters rather than bytes
+func cropColumns(line string, left, width int) string {
+	runes := []rune(line)
+	if left >= len(runes) {
+		return ""
+	}
+	runes = runes[left:]
+	if width > 0 && len(runes) > width {
+		runes = runes[:width]
+	}
+	return string(runes)
+}
+
+// HandleKey reacts to a key press, returning false when the visualisation should stop
+func (t *TUI) HandleKey(key string) bool {
+	switch key {
+	case string(tuiKeyQuit):
+		return false
+	case string(tuiKeyPause):
+		t.paused = !t.paused
+	case string(tuiKeyStep):
+		t.paused = true
+		return t.step() == nil
+	case string(tuiKeyFaster):
+		t.delay = maxDuration(t.delay/2, tuiMinDelay)
+	case string(tuiKeySlower):
+		t.delay = minDuration(t.delay*2, tuiMaxDelay)
+	case tuiKeyUp:
+		t.viewY = maxInt(t.viewY-tuiScrollStep, 0)
+	case tuiKeyDown:
+		t.viewY = minInt(t.viewY+tuiScrollStep, maxInt(t.layout.Height-1, 0))
+	case tuiKeyLeft:
+		t.viewX = maxInt(t.viewX-tuiScrollStep, 0)
+	case tuiKeyRight:
+		t.viewX = minInt(t.viewX+tuiScrollStep, maxInt(t.layout.Width-1, 0))
+	}
+	return true
+}
+
+// SetAfterStep sets a function to call after every step the visualisation plays
+func (t *TUI) SetAfterStep(afterStep func(game *MonsterGame) error) {
+	t.afterStep = afterStep
+}
+
+// step plays the next step of the game, unless it is finished, and calls afterStep
+func (t *TUI) step() error {
+	if t.game.Done() {
+		return nil
+	}
+	t.game.Step()
+	if t.afterStep != nil {
+		t.err = t.afterStep(t.game)
+	}
+	return t.err
+}
+
+// Run redraws the game after every step until the user quits, stepping on a timer unless paused
+// The game may be left unfinished if the user quits early, an error is only returned if afterStep fails
+func (t *TUI) Run(game *MonsterGame, keys <-chan string) error {
+	t.game = game
+	io.WriteString(t.terminal, ansiHideCursor)
+	defer io.WriteString(t.terminal, ansiShowCursor)
+
+	for {
+		io.WriteString(t.terminal, ansiClearScreen+strings.Replace(t.Frame(), "\n", "\r\n", -1))
+
+		var tick <-chan time.Time
+		if !t.paused && !t.game.Done() {
+			tick = time.After(t.delay)
+		}
+		select {
+		case key, ok := <-keys:
+			if !ok || !t.HandleKey(key) {
+				return t.err
+			}
+		case <-tick:
+			if err := t.step(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// NewTUI creates a terminal visualisation of a game played on the world w
+func NewTUI(w *World, style GridStyle, delay time.Duration, terminal io.Writer) *TUI {
+	rows, cols := terminalSize()
+	return &TUI{
+		world:    w,
+		layout:   LayoutWorld(w),
+		style:    style,
+		delay:    delay,
+		rows:     rows,
+		cols:     cols,
+		terminal: terminal,
+	}
+}
+
+// terminalSize asks stty for the size of the terminal, defaulting to 24x80
+func terminalSize() (rows, cols int) {
+	cmd := exec.Command("stty", "size")
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	if err == nil {
+		if _, err := fmt.Sscan(string(out), &rows, &cols); err == nil && rows > 0 && cols > 0 {
+			return rows, cols
+		}
+	}
+	return 24, 80
+}
+
+// readKeys switches the terminal to unbuffered input and sends key presses to the returned channel
+// The returned function restores the terminal
+func readKeys() (<-chan string, func(), error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, nil, fmt.Errorf("The terminal visualisation needs an interactive terminal: %v", err)
+	}
+	if _, err := stty("cbreak", "-echo"); err != nil {
+		return nil, nil, err
+	}
+	restore := func() {
+		stty(strings.TrimSpace(saved))
+	}
+
+	keys := make(chan string)
+	go func() {
+		defer close(keys)
+		buf := make([]byte, 16)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				return
+			}
+			// Arrow keys arrive as a single read of their escape sequence
+			if n >= 3 && buf[0] == 0x1b {
+				keys <- string(buf[:3])
+				continue
+			}
+			for _, b := range buf[:n] {
+				keys <- string(b)
+			}
+		}
+	}()
+	return keys, restore, nil
+}
+
+// stty runs stty with args on the terminal connected to stdin
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
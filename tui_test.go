@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTUIKeys(t *testing.T) {
+	csvData := "Foo north=Bar east=Baz\nBar south=Foo\nBaz west=Foo\n"
+	world, _ := BuildWorldFromRecords(NewCSVReader(strings.NewReader(csvData)).ReadAll())
+	tui := NewTUI(world, ASCIIGrid, 100*time.Millisecond, &strings.Builder{})
+	tui.game = NewMonsterGame(world, 10, 1, WithSeed(1), WithObserver(tui))
+
+	if !strings.Contains(tui.Frame(), "Step 0 ") {
+		t.Errorf("Expected a frame at step 0, got\n%s", tui.Frame())
+	}
+
+	tui.HandleKey("n")
+	if !tui.paused || tui.game.Steps() != 1 {
+		t.Errorf("Expected n to pause and take one step, paused %v after %d steps", tui.paused, tui.game.Steps())
+	}
+	tui.HandleKey(" ")
+	if tui.paused {
+		t.Error("Expected space to resume the game")
+	}
+
+	for i := 0; i < 10; i++ {
+		tui.HandleKey("+")
+	}
+	if tui.delay != tuiMinDelay {
+		t.Errorf("Expected the delay to stop at %v, got %v", tuiMinDelay, tui.delay)
+	}
+	for i := 0; i < 10; i++ {
+		tui.HandleKey("-")
+	}
+	if tui.delay != tuiMaxDelay {
+		t.Errorf("Expected the delay to stop at %v, got %v", tuiMaxDelay, tui.delay)
+	}
+
+	if tui.HandleKey("q") {
+		t.Error("Expected q to stop the visualisation")
+	}
+}
+
+func TestTUIAfterStep(t *testing.T) {
+	csvData := "Foo north=Bar east=Baz\nBar south=Foo\nBaz west=Foo\n"
+	world, _ := BuildWorldFromRecords(NewCSVReader(strings.NewReader(csvData)).ReadAll())
+	tui := NewTUI(world, ASCIIGrid, 100*time.Millisecond, &strings.Builder{})
+	tui.game = NewMonsterGame(world, 10, 1, WithSeed(1), WithObserver(tui))
+
+	saved := 0
+	tui.SetAfterStep(func(game *MonsterGame) error {
+		saved = game.Steps()
+		return nil
+	})
+	if !tui.HandleKey("n") || saved != 1 {
+		t.Errorf("Expected the step to be followed by a save, saved at step %d", saved)
+	}
+	tui.SetAfterStep(func(game *MonsterGame) error { return errors.New("disk full") })
+	if tui.HandleKey("n") {
+		t.Error("Expected a failed save to stop the visualisation")
+	}
+}
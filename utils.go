@@ -12,18 +12,23 @@ const (
 
 var seededRand = rand.New(rand.NewSource(time.Now().UnixNano()))
 
-func randomFromCharset(charset string) byte {
-	return charset[seededRand.Intn(len(charset))]
+func randomFromCharset(r *rand.Rand, charset string) byte {
+	return charset[r.Intn(len(charset))]
 }
 
 // GenerateIdentifier constructs a random human readable indentifier of a specific length
 func GenerateIdentifier(length int) string {
+	return GenerateIdentifierFrom(seededRand, length)
+}
+
+// GenerateIdentifierFrom constructs a human readable indentifier of a specific length using the provided random source
+func GenerateIdentifierFrom(r *rand.Rand, length int) string {
 	b := make([]byte, length)
 	for i := range b {
 		if i%2 == 0 {
-			b[i] = randomFromCharset(vowels)
+			b[i] = randomFromCharset(r, vowels)
 		} else {
-			b[i] = randomFromCharset(consonants)
+			b[i] = randomFromCharset(r, consonants)
 		}
 	}
 	return string(b)
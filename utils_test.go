@@ -1 +1,17 @@
 package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestGenerateIdentifierFromIsReproducible(t *testing.T) {
+	first := GenerateIdentifierFrom(rand.New(rand.NewSource(7)), 8)
+	second := GenerateIdentifierFrom(rand.New(rand.NewSource(7)), 8)
+	if first != second {
+		t.Errorf("Expected identifiers from equally seeded sources to match, got %s and %s", first, second)
+	}
+	if len(first) != 8 {
+		t.Errorf("Expected identifier of length 8, got %d", len(first))
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// MapProblem is an inconsistency found in world map data
+type MapProblem struct {
+	Line    int      // Line of the record with the problem, 0 if unknown
+	City    CityName // City whose record has the problem, empty for unparseable lines
+	Message string
+}
+
+func (p MapProblem) String() string {
+	if p.City == "" {
+		return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+	}
+	return fmt.Sprintf("line %d: %s: %s", p.Line, p.City, p.Message)
+}
+
+// MapValidator checks world map records for problems which BuildWorldFromRecords silently tolerates
+type MapValidator struct {
+	declared map[CityName]int // Line on which each city has its own record
+}
+
+// CheckRecord checks a single record, records must be checked in the order they are read
+func (v *MapValidator) CheckRecord(record *WorldRecord) []MapProblem {
+	var problems []MapProblem
+	report := func(format string, args ...interface{}) {
+		problems = append(problems, MapProblem{Line: record.Line, City: record.City, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if record.Err != nil {
+		// The problem already says which line it is on
+		message := record.Err.Error()
+		if parseErr, ok := record.Err.(*ParseError); ok {
+			message = parseErr.detail()
+		}
+		return []MapProblem{{Line: record.Line, Message: message}}
+	}
+
+	if line, ok := v.declared[record.City]; ok {
+		report("city is already defined on line %d", line)
+	} else {
+		v.declared[record.City] = record.Line
+	}
+
+	if len(record.Roads) > maxRoadsPerCity {
+		report("has %d roads, at most %d are allowed", len(record.Roads), maxRoadsPerCity)
+	}
+
+	seen := make(map[string]bool)
+	for _, road := range record.Roads {
+		if !IsCompassDirection(road.Direction) {
+			report("unknown direction %q, expected north, south, east or west", road.Direction)
+		} else if seen[road.Direction] {
+			report("has more than one road leading %s", road.Direction)
+		}
+		seen[road.Direction] = true
+
+		if road.Destination == record.City {
+			report("road %s leads back to the city itself", road.Direction)
+		}
+	}
+	return problems
+}
+
+// CheckWorld checks the roads between cities once every record has been checked and the world built from them
+func (v *MapValidator) CheckWorld(w *World) []MapProblem {
+	return append(v.CheckDestinations(w), v.CheckReverseRoads(w)...)
+}
+
+// CheckDestinations reports roads leading to cities which have no record of their own
+func (v *MapValidator) CheckDestinations(w *World) []MapProblem {
+	var problems []MapProblem
+	for _, city := range w.GetCities() {
+		line, declared := v.declared[city.Name]
+		if !declared {
+			continue
+		}
+		for _, road := range w.GetRoads(city.Name) {
+			if _, ok := v.declared[road.Destination]; !ok {
+				problems = append(problems, MapProblem{
+					Line:    line,
+					City:    city.Name,
+					Message: fmt.Sprintf("road %s leads to %s which has no line of its own", road.Direction, road.Destination),
+				})
+			}
+		}
+	}
+	return problems
+}
+
+// CheckReverseRoads reports roads between declared cities without a road in the opposite direction leading back
+func (v *MapValidator) CheckReverseRoads(w *World) []MapProblem {
+	var problems []MapProblem
+	for _, city := range w.GetCities() {
+		line, declared := v.declared[city.Name]
+		if !declared {
+			continue
+		}
+		for _, road := range w.GetRoads(city.Name) {
+			opposite, ok := OppositeDirection(road.Direction)
+			if _, declared := v.declared[road.Destination]; !ok || !declared || road.Destination == city.Name {
+				// Already reported by CheckRecord or CheckDestinations
+				continue
+			}
+			if problem := checkReverseRoad(w, road, opposite); problem != "" {
+				problems = append(problems, MapProblem{Line: line, City: city.Name, Message: problem})
+			}
+		}
+	}
+	return problems
+}
+
+// forget undoes the declaration of a city made when checking a record which is then left out of the world
+func (v *MapValidator) forget(record *WorldRecord) {
+	if line, ok := v.declared[record.City]; ok && line == record.Line {
+		delete(v.declared, record.City)
+	}
+}
+
+// checkReverseRoad describes what is wrong with the road leading back along road, or returns "" if it exists
+func checkReverseRoad(w *World, road *Road, opposite string) string {
+	var back *Road
+	for _, candidate := range w.GetRoads(road.Destination) {
+		if candidate.Direction == opposite {
+			back = candidate
+			break
+		}
+	}
+	if back != nil && back.Destination == road.Source {
+		return ""
+	}
+	if back != nil {
+		return fmt.Sprintf("road %s leads to %s, but its road %s leads to %s instead of back here", road.Direction, road.Destination, opposite, back.Destination)
+	}
+	for _, candidate := range w.GetRoads(road.Destination) {
+		if candidate.Destination == road.Source {
+			return fmt.Sprintf("road %s leads to %s, but its road back here leads %s instead of %s", road.Direction, road.Destination, candidate.Direction, opposite)
+		}
+	}
+	return fmt.Sprintf("road %s leads to %s, which has no road %s back here", road.Direction, road.Destination, opposite)
+}
+
+// NewMapValidator creates a MapValidator with no records checked
+func NewMapValidator() *MapValidator {
+	return &MapValidator{declared: make(map[CityName]int)}
+}
+
+// ValidateMap builds a World from records with BuildWorldFromRecords, reporting every problem found on the way ordered by line
+// Unparseable records are reported as problems and left out of the World
+func ValidateMap(records <-chan *WorldRecord) (*World, []MapProblem, error) {
+	v := NewMapValidator()
+	var problems []MapProblem
+
+	w, err := buildWorldFiltered(records, func(record *WorldRecord) bool {
+		problems = append(problems, v.CheckRecord(record)...)
+		return record.Err == nil
+	})
+	if err != nil {
+		return nil, problems, err
+	}
+	problems = append(problems, v.CheckWorld(w)...)
+	sortProblems(problems)
+	return w, problems, nil
+}
+
+// buildWorldFiltered builds a World with BuildWorldFromRecords from the records which keep accepts
+func buildWorldFiltered(records <-chan *WorldRecord, keep func(record *WorldRecord) bool) (*World, error) {
+	type buildResult struct {
+		world *World
+		err   error
+	}
+	kept := make(chan *WorldRecord)
+	built := make(chan buildResult)
+	go func() {
+		w, err := BuildWorldFromRecords(kept)
+		built <- buildResult{w, err}
+	}()
+
+	for record := range records {
+		if keep(record) {
+			kept <- record
+		}
+	}
+	close(kept)
+
+	result := <-built
+	return result.world, result.err
+}
+
+// sortProblems orders problems by line, keeping the order of problems on the same line
+func sortProblems(problems []MapProblem) {
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+}
+
+// runValidate implements the validate command, reporting problems with a map without playing a game
+func runValidate(args []string) error {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	mapDataFn := flags.String("d", defaultMapDataFn, "input file path containing the map to check")
+	inputFormat := flags.String("if", "", "format of the map data file, \"csv\" or \"json\" (default: guessed from the file extension)")
+	flags.Parse(args)
+
+	file, err := os.Open(*mapDataFn)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	r, err := openWorldStateReader(*mapDataFn, *inputFormat, file)
+	if err != nil {
+		return err
+	}
+	_, problems, err := ValidateMap(r.ReadAll())
+	if err != nil {
+		return fmt.Errorf("%s: %v", *mapDataFn, err)
+	}
+	for _, problem := range problems {
+		fmt.Printf("%s: %s\n", *mapDataFn, problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s: %d problems found", *mapDataFn, len(problems))
+	}
+	fmt.Printf("%s: no problems found\n", *mapDataFn)
+	return nil
+}
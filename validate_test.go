@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidateMap(t *testing.T) {
+	csvData := strings.Join([]string{
+		"Foo north=Bar south=Baz east=Qux",
+		"Bar south=Foo up=Baz",
+		"Baz north=Foo north=Qux west=Baz",
+		"Qux west=Foo east=Bee",
+		"Foo west=Qux",
+		"Zip north=Zap south=Foo east=Bar west=Baz north=Qux",
+		"Zap south",
+	}, "\n")
+
+	_, problems, err := ValidateMap(NewCSVReader(strings.NewReader(csvData)).ReadAll())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"line 2: Bar: unknown direction \"up\", expected north, south, east or west",
+		"line 3: Baz: has more than one road leading north",
+		"line 3: Baz: road west leads back to the city itself",
+		"line 4: Qux: road east leads to Bee which has no line of its own",
+		"line 5: Foo: city is already defined on line 1",
+		"line 6: Zip: has 5 roads, at most 4 are allowed",
+		"line 6: Zip: has more than one road leading north",
+		"line 7: column 5: \"south\": expected a road in the form direction=city",
+	}
+	found := make(map[string]bool)
+	for _, problem := range problems {
+		found[problem.String()] = true
+	}
+	for _, msg := range expected {
+		if !found[msg] {
+			t.Errorf("Expected problem %q", msg)
+		}
+	}
+	// Zap's line can't be parsed, so it never gets a line of its own
+	if !found["line 6: Zip: road north leads to Zap which has no line of its own"] {
+		t.Errorf("Expected Zip's road to Zap to be reported")
+	}
+}
+
+func TestValidateMapReverseRoads(t *testing.T) {
+	csvData := strings.Join([]string{
+		"Foo north=Bar east=Baz west=Qux",
+		"Bar south=Foo",
+		"Baz north=Foo",
+		"Qux east=Bar",
+	}, "\n")
+
+	_, problems, err := ValidateMap(NewCSVReader(strings.NewReader(csvData)).ReadAll())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"line 1: Foo: road east leads to Baz, but its road back here leads north instead of west",
+		"line 1: Foo: road west leads to Qux, but its road east leads to Bar instead of back here",
+		"line 3: Baz: road north leads to Foo, but its road back here leads east instead of south",
+		"line 4: Qux: road east leads to Bar, which has no road west back here",
+	}
+	if len(problems) != len(expected) {
+		t.Errorf("Expected %d problems, got %d: %v", len(expected), len(problems), problems)
+	}
+	for i := range expected {
+		if i < len(problems) && problems[i].String() != expected[i] {
+			t.Errorf("Expected problem %q, got %q", expected[i], problems[i].String())
+		}
+	}
+}
+
+func TestValidateMapAcceptsProvidedMaps(t *testing.T) {
+	for _, fn := range []string{"assets/world_map_small.txt", "assets/world_map_medium.txt"} {
+		file, err := os.Open(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		world, problems, err := ValidateMap(NewCSVReader(file).ReadAll())
+		file.Close()
+		if err != nil || world == nil || len(problems) != 0 {
+			t.Errorf("Expected %s to be valid, got %v", fn, problems)
+		}
+	}
+}
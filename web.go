@@ -0,0 +1,22 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// webFiles is the browser front end, built into the binary so that it can be served without the source tree
+//
+//go:embed web
+var webFiles embed.FS
+
+// webHandler serves the browser front end, which plays games through the GameServer API
+func webHandler() http.Handler {
+	files, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		// The web directory is embedded above, so it is always there
+		panic(err)
+	}
+	return http.FileServer(http.FS(files))
+}
@@ -0,0 +1,305 @@
+// Browser front end for the monster game, playing games through the serve command's API
+(function () {
+  "use strict";
+
+  // Size of a grid cell in pixels, cities are drawn in the middle of their cell
+  var CELL = 64;
+  var CITY_WIDTH = 52;
+  var CITY_HEIGHT = 30;
+  var MONSTER_RADIUS = 4;
+  var SVG_NS = "http://www.w3.org/2000/svg";
+
+  var $ = function (id) { return document.getElementById(id); };
+
+  var game = null;      // Summary of the game being played, as returned by the API
+  var positions = {};   // Grid position of each city by name
+  var cities = {};      // SVG group of each city by name
+  var monsters = {};    // Each monster by id: {name, city, status, dot}
+  var stream = null;    // EventSource of the game's events
+  var timer = null;     // Interval stepping the game while it is playing
+  var stepping = false; // Whether a step request is in flight
+  var redraw = false;   // Whether monsters need placing on the next animation frame
+
+  function showError(message) {
+    $("error").textContent = message;
+    $("error").hidden = !message;
+  }
+
+  // api calls the game API, resolving to the decoded JSON response
+  function api(method, path, body) {
+    return fetch(path, {method: method, body: body}).then(function (resp) {
+      return resp.json().then(function (data) {
+        if (!resp.ok) {
+          throw new Error(data.error || resp.statusText);
+        }
+        return data;
+      });
+    });
+  }
+
+  function svg(name, attrs, parent) {
+    var el = document.createElementNS(SVG_NS, name);
+    Object.keys(attrs).forEach(function (key) { el.setAttribute(key, attrs[key]); });
+    if (parent) {
+      parent.appendChild(el);
+    }
+    return el;
+  }
+
+  function centre(city) {
+    var pos = positions[city];
+    return {x: pos.x * CELL + CELL / 2, y: pos.y * CELL + CELL / 2};
+  }
+
+  // drawMap draws the roads and cities of a snapshot on the grid worked out by the server
+  function drawMap(layout, snapshot) {
+    var map = $("map");
+    map.innerHTML = "";
+    map.setAttribute("width", Math.max(layout.width, 1) * CELL);
+    map.setAttribute("height", Math.max(layout.height, 1) * CELL);
+    positions = {};
+    layout.cities.forEach(function (c) { positions[c.name] = c; });
+
+    var roads = svg("g", {}, map);
+    snapshot.cities.forEach(function (city) {
+      city.roads.forEach(function (road) {
+        var from = centre(city.name), to = centre(road.destination);
+        svg("line", {"class": "road", x1: from.x, y1: from.y, x2: to.x, y2: to.y}, roads);
+      });
+    });
+
+    cities = {};
+    snapshot.cities.forEach(function (city) {
+      var c = centre(city.name);
+      var group = svg("g", {"class": "city" + (city.destroyed ? " destroyed" : "")}, map);
+      svg("rect", {x: c.x - CITY_WIDTH / 2, y: c.y - CITY_HEIGHT / 2, width: CITY_WIDTH, height: CITY_HEIGHT, rx: 4}, group);
+      var label = svg("text", {x: c.x, y: c.y - 3}, group);
+      label.textContent = city.name.length > 9 ? city.name.slice(0, 8) + "…" : city.name;
+      svg("title", {}, group).textContent = city.name;
+      cities[city.name] = group;
+    });
+
+    monsters = {};
+    snapshot.monsters.forEach(function (m) {
+      addMonster(m.id, m.name, m.city, m.status);
+    });
+    placeMonsters();
+  }
+
+  function addMonster(id, name, city, status) {
+    var dot = svg("circle", {"class": "monster", r: MONSTER_RADIUS}, $("map"));
+    svg("title", {}, dot).textContent = "Monster " + name;
+    monsters[id] = {name: name, city: city, status: status, dot: dot};
+  }
+
+  // placeMonsters lines up the living monsters of each city along the bottom of the city
+  function placeMonsters() {
+    redraw = false;
+    var counts = {};
+    Object.keys(monsters).forEach(function (id) {
+      var m = monsters[id];
+      if (m.status === "dead" || !positions[m.city]) {
+        m.dot.style.display = "none";
+        return;
+      }
+      var i = counts[m.city] || 0;
+      counts[m.city] = i + 1;
+      var c = centre(m.city);
+      m.dot.style.display = "";
+      m.dot.setAttribute("cx", c.x - CITY_WIDTH / 2 + MONSTER_RADIUS * 2 + (i % 5) * MONSTER_RADIUS * 2.5);
+      m.dot.setAttribute("cy", c.y + CITY_HEIGHT / 2 - MONSTER_RADIUS - 1 - Math.floor(i / 5) * MONSTER_RADIUS * 2.5);
+      m.dot.setAttribute("class", "monster" + (m.status === "trapped" ? " trapped" : ""));
+    });
+  }
+
+  function scheduleRedraw() {
+    if (!redraw) {
+      redraw = true;
+      window.requestAnimationFrame(placeMonsters);
+    }
+  }
+
+  function log(message) {
+    var item = document.createElement("li");
+    item.textContent = message;
+    $("log").appendChild(item);
+    $("log").scrollTop = $("log").scrollHeight;
+  }
+
+  function setStatus(text) {
+    $("status").textContent = text;
+  }
+
+  // onEvent applies a game event from the stream to the drawing
+  function onEvent(e) {
+    var event = JSON.parse(e.data);
+    switch (event.type) {
+      case "spawned":
+        addMonster(event.monster.id, event.monster.name, event.city, "active");
+        break;
+      case "moved":
+        monsters[event.monster.id].city = event.to;
+        break;
+      case "trapped":
+        monsters[event.monster.id].status = "trapped";
+        break;
+      case "destroyed":
+        event.monsters.forEach(function (m) { monsters[m.id].status = "dead"; });
+        var city = cities[event.city];
+        city.setAttribute("class", "city destroyed flash");
+        window.setTimeout(function () { city.setAttribute("class", "city destroyed"); }, 1000);
+        log("[" + event.step + "] " + event.message);
+        break;
+      case "battle":
+      case "road-battle":
+        event.monsters.forEach(function (m) { monsters[m.id].status = "dead"; });
+        log("[" + event.step + "] " + event.message);
+        break;
+      case "ended":
+        stop();
+        showSummary(event);
+        break;
+    }
+    setStatus("Step " + event.step);
+    scheduleRedraw();
+  }
+
+  function showSummary(event) {
+    var destroyed = Object.keys(cities).filter(function (name) {
+      return cities[name].getAttribute("class").indexOf("destroyed") >= 0;
+    });
+    var summary = $("summary");
+    summary.innerHTML = "";
+    var heading = document.createElement("h2");
+    heading.textContent = "Game over after " + event.step + " steps: " + event.reason;
+    summary.appendChild(heading);
+    var counts = document.createElement("p");
+    counts.textContent = destroyed.length + " of " + Object.keys(cities).length + " cities destroyed, " +
+      (event.survivors || []).length + " monsters survived";
+    summary.appendChild(counts);
+    if (event.message) {
+      var survivors = document.createElement("pre");
+      survivors.textContent = event.message;
+      summary.appendChild(survivors);
+    }
+    summary.hidden = false;
+    ["play", "step", "finish"].forEach(function (id) { $(id).disabled = true; });
+  }
+
+  function step() {
+    if (stepping || !game) {
+      return;
+    }
+    stepping = true;
+    api("POST", "/games/" + game.id + "/step").then(function (result) {
+      stepping = false;
+      if (result.game.done) {
+        stop();
+      }
+    }).catch(function (err) {
+      stepping = false;
+      stop();
+      showError(err.message);
+    });
+  }
+
+  function play() {
+    stop();
+    timer = window.setInterval(step, Number($("speed").value));
+    $("play").textContent = "Pause";
+  }
+
+  function stop() {
+    if (timer !== null) {
+      window.clearInterval(timer);
+      timer = null;
+    }
+    $("play").textContent = "Play";
+  }
+
+  function start(ev) {
+    ev.preventDefault();
+    showError("");
+    stop();
+    if (stream) {
+      stream.close();
+    }
+    var query = "n=" + encodeURIComponent($("monsters").value) +
+      "&max=" + encodeURIComponent($("iterations").value) +
+      "&format=" + encodeURIComponent($("map-format").value);
+    if ($("seed").value !== "") {
+      query += "&seed=" + encodeURIComponent($("seed").value);
+    }
+    api("POST", "/games?" + query, $("map-data").value).then(function (created) {
+      game = created;
+      return api("GET", "/games/" + game.id + "/layout");
+    }).then(function (layout) {
+      $("log").innerHTML = "";
+      $("summary").hidden = true;
+      ["play", "step", "finish"].forEach(function (id) { $(id).disabled = false; });
+      $("game").hidden = false;
+      if (layout.conflicts.length > 0) {
+        showError("Some roads could not be laid out: " + layout.conflicts.join("; "));
+      }
+      setStatus("Seed " + game.seed);
+
+      // The stream starts with a snapshot of the game, which the map is drawn from
+      stream = new EventSource("/games/" + game.id + "/events");
+      stream.addEventListener("snapshot", function (e) {
+        var snapshot = JSON.parse(e.data);
+        drawMap(layout, snapshot);
+        $("log").innerHTML = "";
+        snapshot.history.forEach(function (event) { log("[" + event.step + "] " + event.message); });
+        // A game which is already over won't send its end again, so the summary is shown from the snapshot
+        if (snapshot.game.done) {
+          stop();
+          showSummary({
+            step: snapshot.game.steps,
+            reason: snapshot.game.end_reason,
+            survivors: snapshot.monsters.filter(function (m) { return m.status !== "dead"; })
+          });
+        }
+      });
+      ["spawned", "moved", "trapped", "destroyed", "battle", "road-battle", "ended"].forEach(function (type) {
+        stream.addEventListener(type, onEvent);
+      });
+    }).catch(function (err) {
+      showError(err.message);
+    });
+  }
+
+  $("map-file").addEventListener("change", function () {
+    var file = this.files[0];
+    if (!file) {
+      return;
+    }
+    $("map-format").value = /\.json$/i.test(file.name) ? "json" : "csv";
+    file.text().then(function (text) { $("map-data").value = text; });
+  });
+  $("new-game").addEventListener("submit", start);
+  $("play").addEventListener("click", function () {
+    if (timer === null) {
+      play();
+    } else {
+      stop();
+    }
+  });
+  $("step").addEventListener("click", function () {
+    stop();
+    step();
+  });
+  // Finish runs the rest of the game on the server, with the chosen delay so that it is still animated
+  $("finish").addEventListener("click", function () {
+    stop();
+    ["play", "step", "finish"].forEach(function (id) { $(id).disabled = true; });
+    api("POST", "/games/" + game.id + "/run?delay=" + encodeURIComponent($("speed").value + "ms")).catch(function (err) {
+      ["play", "step", "finish"].forEach(function (id) { $(id).disabled = false; });
+      showError(err.message);
+    });
+  });
+  $("speed").addEventListener("change", function () {
+    if (timer !== null) {
+      play();
+    }
+  });
+}());
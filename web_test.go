@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebHandler(t *testing.T) {
+	for _, path := range []string{"/", "/app.js", "/style.css"} {
+		rec := httptest.NewRecorder()
+		webHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+			t.Errorf("Expected %s to be served, got status %d", path, rec.Code)
+		}
+	}
+}
+
+func TestGameServerLayout(t *testing.T) {
+	server := NewGameServer()
+	serverRequest(t, server, http.MethodPost, "/games?n=1&seed=1", serverTestMap, http.StatusCreated, nil)
+
+	var layout layoutSummary
+	serverRequest(t, server, http.MethodGet, "/games/1/layout", "", http.StatusOK, &layout)
+	expected := map[CityName]GridPosition{"Foo": {0, 1}, "Bar": {0, 0}, "Baz": {1, 1}}
+	if layout.Width != 2 || layout.Height != 2 || len(layout.Cities) != 3 || len(layout.Conflicts) != 0 {
+		t.Fatalf("Unexpected layout %+v", layout)
+	}
+	for _, city := range layout.Cities {
+		if pos := expected[city.Name]; pos.X != city.X || pos.Y != city.Y {
+			t.Errorf("Expected %s at %v, got %+v", city.Name, pos, city)
+		}
+	}
+}
@@ -9,6 +9,36 @@ type Road struct {
 	Destination CityName
 }
 
+// Compass directions which roads lead in
+const (
+	North = "north"
+	South = "south"
+	East  = "east"
+	West  = "west"
+)
+
+// maxRoadsPerCity is the number of compass directions, each city has at most one road in each
+const maxRoadsPerCity = 4
+
+var oppositeDirections = map[string]string{
+	North: South,
+	South: North,
+	East:  West,
+	West:  East,
+}
+
+// OppositeDirection returns the direction of the road leading back, and false if dir isn't a compass direction
+func OppositeDirection(dir string) (string, bool) {
+	opposite, ok := oppositeDirections[dir]
+	return opposite, ok
+}
+
+// IsCompassDirection checks whether dir is one of north, south, east or west
+func IsCompassDirection(dir string) bool {
+	_, ok := oppositeDirections[dir]
+	return ok
+}
+
 // NewRoad returns a Road type edge
 func NewRoad(dir string, src, dest CityName) *Road {
 	return &Road{Direction: dir, Destination: dest, Source: src}
@@ -18,6 +48,7 @@ func NewRoad(dir string, src, dest CityName) *Road {
 type World struct {
 	Cities map[CityName]*City   // Nodes in the graph
 	Roads  map[CityName][]*Road // Edges in the graph
+	order  []CityName           // City names in insertion order, so that iteration is deterministic
 }
 
 // NewWorld Create world map
@@ -34,6 +65,7 @@ func (w *World) AddCity(city *City) bool {
 		return false
 	}
 	w.Cities[city.Name] = city
+	w.order = append(w.order, city.Name)
 	return true
 }
 
@@ -47,10 +79,90 @@ func (w *World) AddRoad(road *Road) {
 	w.Roads[road.Source] = append(w.Roads[road.Source], road)
 }
 
-// GetUndestroyedCities returns a list of cities which haven't been destroyed
+// Copy creates an independent copy of the world's cities and roads, without any monsters
+// so that many games can be played on the same map at once
+func (w *World) Copy() *World {
+	world := NewWorld()
+	for _, city := range w.GetCities() {
+		copied := NewCity(city.Name, city.maxMonsters)
+		copied.Destroyed = city.Destroyed
+		copied.Attributes = append([]CityAttribute(nil), city.Attributes...)
+		world.AddCity(copied)
+	}
+	for _, city := range w.GetCities() {
+		for _, road := range w.GetRoads(city.Name) {
+			world.AddRoad(NewRoad(road.Direction, road.Source, road.Destination))
+		}
+	}
+	return world
+}
+
+// SetDefaultCapacity changes the capacity of every city which isn't given a capacity attribute in the map data
+func (w *World) SetDefaultCapacity(capacity int) {
+	for _, city := range w.GetCities() {
+		explicit := false
+		for _, attr := range city.Attributes {
+			if attr.Key == capacityAttribute {
+				explicit = true
+			}
+		}
+		if !explicit {
+			city.maxMonsters = capacity
+		}
+	}
+}
+
+// LargestSurvivingRegion counts the cities in the largest group of undestroyed cities connected by roads
+// Roads are followed in either direction, as a one way road still joins two cities
+func (w *World) LargestSurvivingRegion() int {
+	neighbours := make(map[CityName][]CityName)
+	for _, city := range w.GetUndestroyedCities() {
+		for _, road := range w.GetRoads(city.Name) {
+			if dest := w.GetCity(road.Destination); dest != nil && !dest.Destroyed {
+				neighbours[city.Name] = append(neighbours[city.Name], dest.Name)
+				neighbours[dest.Name] = append(neighbours[dest.Name], city.Name)
+			}
+		}
+	}
+
+	largest := 0
+	seen := make(map[CityName]bool)
+	for _, city := range w.GetUndestroyedCities() {
+		if seen[city.Name] {
+			continue
+		}
+		seen[city.Name] = true
+		size := 0
+		queue := []CityName{city.Name}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			size++
+			for _, next := range neighbours[current] {
+				if !seen[next] {
+					seen[next] = true
+					queue = append(queue, next)
+				}
+			}
+		}
+		largest = maxInt(largest, size)
+	}
+	return largest
+}
+
+// GetCities returns a list of all cities in the order they were added
+func (w *World) GetCities() []*City {
+	cities := make([]*City, len(w.order))
+	for i, cityName := range w.order {
+		cities[i] = w.Cities[cityName]
+	}
+	return cities
+}
+
+// GetUndestroyedCities returns a list of cities which haven't been destroyed in the order they were added
 func (w *World) GetUndestroyedCities() []*City {
 	var undestroyed []*City
-	for _, city := range w.Cities {
+	for _, city := range w.GetCities() {
 		if !city.Destroyed {
 			undestroyed = append(undestroyed, city)
 		}
@@ -72,6 +184,27 @@ func (w *World) FindPossibleDestinations(cityName CityName) ([]*City, error) {
 	return possibleDestinations, nil
 }
 
+// MoveOption is a road a monster may take and the undestroyed city it leads to
+type MoveOption struct {
+	Road *Road
+	City *City
+}
+
+// FindPossibleMoves returns the roads out of a given city which lead to undestroyed cities, in the order of the roads
+func (w *World) FindPossibleMoves(cityName CityName) ([]MoveOption, error) {
+	var moves []MoveOption
+	for _, road := range w.GetRoads(cityName) {
+		dest, ok := w.Cities[road.Destination]
+		if !ok {
+			return nil, fmt.Errorf("Error finding destination city %s: doesn't exist", road.Destination)
+		}
+		if !dest.Destroyed {
+			moves = append(moves, MoveOption{Road: road, City: dest})
+		}
+	}
+	return moves, nil
+}
+
 // GetCity returns a pointer to a City by its name
 func (w *World) GetCity(cityName CityName) *City {
 	return w.Cities[cityName]
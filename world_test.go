@@ -1 +1,54 @@
 package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWorldKeepsCityInsertionOrder(t *testing.T) {
+	world := NewWorld()
+	names := []CityName{"Foo", "Bar", "Baz", "Qu-ux", "Bee"}
+	for _, name := range names {
+		world.AddCity(NewCity(name, 2))
+	}
+	world.GetCity("Baz").Destroy()
+
+	for i, city := range world.GetCities() {
+		if city.Name != names[i] {
+			t.Errorf("Expected %s at position %d, got %s", names[i], i, city.Name)
+		}
+	}
+
+	expected := []CityName{"Foo", "Bar", "Qu-ux", "Bee"}
+	for i, city := range world.GetUndestroyedCities() {
+		if city.Name != expected[i] {
+			t.Errorf("Expected undestroyed %s at position %d, got %s", expected[i], i, city.Name)
+		}
+	}
+}
+
+func TestWorldCopy(t *testing.T) {
+	csvData := "Foo north=Bar capacity=3\nBar south=Foo\n"
+	world, _ := BuildWorldFromRecords(NewCSVReader(strings.NewReader(csvData)).ReadAll())
+	copied := world.Copy()
+	copied.GetCity("Bar").Destroy()
+
+	if world.GetCity("Bar").Destroyed {
+		t.Error("Expected destroying a city of the copy to leave the original alone")
+	}
+	if copied.GetCity("Foo").Capacity() != 3 || len(copied.GetRoads("Foo")) != 1 || copied.GetCities()[0].Name != "Foo" {
+		t.Errorf("Expected the copy to keep capacities, roads and city order")
+	}
+}
+
+func TestWorldLargestSurvivingRegion(t *testing.T) {
+	csvData := "A east=B\nB west=A east=C\nC west=B east=D\nD west=C\nE north=F\nF\n"
+	world, _ := BuildWorldFromRecords(NewCSVReader(strings.NewReader(csvData)).ReadAll())
+	if size := world.LargestSurvivingRegion(); size != 4 {
+		t.Errorf("Expected a region of 4 cities, got %d", size)
+	}
+	world.GetCity("B").Destroy()
+	if size := world.LargestSurvivingRegion(); size != 2 {
+		t.Errorf("Expected the largest region to be split, got %d", size)
+	}
+}
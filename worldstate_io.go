@@ -1,15 +1,49 @@
 package main
 
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
 // WorldRecord is generic representation of a city and roads which lead out of it useful in managing data from different formats
 type WorldRecord struct {
-	City  CityName
-	Roads []*Road
+	City       CityName
+	Roads      []*Road
+	Attributes []CityAttribute // Optional properties of the city, e.g. capacity=3
+	Destroyed  bool            // Whether the city has been destroyed, only snapshot records include destroyed cities
+	Monsters   []string        // Names of the monsters in the city, for a destroyed city the monsters which destroyed it, only set in snapshot records
+	Line       int             // Line of the input the record was read from, 0 if unknown
+	Err        error           // Set instead of the other fields when the input couldn't be parsed, usually to a *ParseError
+}
+
+// ParseError describes a problem found while reading world map data
+type ParseError struct {
+	Line   int    // Line of the input, starting at 1
+	Column int    // Column of the offending token, starting at 1
+	Token  string // The offending token, empty if the problem isn't with a particular token
+	Reason string // What is wrong
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d, %s", e.Line, e.detail())
+}
+
+// detail describes the error without saying which line it is on
+func (e *ParseError) detail() string {
+	if e.Token == "" {
+		return fmt.Sprintf("column %d: %s", e.Column, e.Reason)
+	}
+	return fmt.Sprintf("column %d: %q: %s", e.Column, e.Token, e.Reason)
 }
 
 // WorldStateReader is an generic interface for reading in the state of a World
 type WorldStateReader interface {
 	// ReadAll: Reads CSV data and returns a channel which it feeds records
 	// All world map data input sources should be handled using this interface
+	// Input which can't be parsed is reported with a record holding only an Err, readers may carry on with the following input
 	ReadAll() (ch chan *WorldRecord)
 }
 
@@ -20,37 +54,257 @@ type WorldStateWriter interface {
 	WriteAll(ch <-chan *WorldRecord)
 }
 
+// worldStateReaders creates a WorldStateReader for each supported map data format
+var worldStateReaders = map[string]func(r io.Reader) WorldStateReader{
+	"csv":  func(r io.Reader) WorldStateReader { return NewCSVReader(r) },
+	"json": func(r io.Reader) WorldStateReader { return NewJSONReader(r) },
+}
+
+// worldStateWriters creates a WorldStateWriter for each supported map data format
+var worldStateWriters = map[string]func(w io.Writer) WorldStateWriter{
+	"csv":  func(w io.Writer) WorldStateWriter { return NewCSVWriter(w) },
+	"json": func(w io.Writer) WorldStateWriter { return NewJSONWriter(w) },
+	"dot":  func(w io.Writer) WorldStateWriter { return NewDOTWriter(w) },
+}
+
+// formatExtensions maps file extensions to the map data format they hold, any other extension is read as csv
+var formatExtensions = map[string]string{
+	".json": "json",
+	".dot":  "dot",
+	".gv":   "dot",
+}
+
+// FormatFromPath guesses the map data format of a file from its extension
+func FormatFromPath(path string) string {
+	if format, ok := formatExtensions[strings.ToLower(filepath.Ext(path))]; ok {
+		return format
+	}
+	return "csv"
+}
+
+// NewWorldStateReader creates a WorldStateReader for a format, e.g. "csv" or "json"
+func NewWorldStateReader(format string, r io.Reader) (WorldStateReader, error) {
+	newReader, ok := worldStateReaders[format]
+	if !ok {
+		var names []string
+		for name := range worldStateReaders {
+			names = append(names, name)
+		}
+		return nil, unknownFormatError("input", format, names)
+	}
+	return newReader(r), nil
+}
+
+// NewWorldStateWriter creates a WorldStateWriter for a format, e.g. "csv" or "json"
+func NewWorldStateWriter(format string, w io.Writer) (WorldStateWriter, error) {
+	if err := checkOutputFormat(format); err != nil {
+		return nil, err
+	}
+	return worldStateWriters[format](w), nil
+}
+
+// checkOutputFormat checks that there is a WorldStateWriter for a format
+func checkOutputFormat(format string) error {
+	if _, ok := worldStateWriters[format]; ok {
+		return nil
+	}
+	var names []string
+	for name := range worldStateWriters {
+		names = append(names, name)
+	}
+	return unknownFormatError("output", format, names)
+}
+
+// unknownFormatError describes a format which isn't supported, listing the ones which are
+func unknownFormatError(kind, format string, supported []string) error {
+	sort.Strings(supported)
+	return fmt.Errorf("Unknown %s format %q, expected one of %s", kind, format, strings.Join(supported, ", "))
+}
+
 // BuildWorldFromRecords generates a World graph from records passed through a channel (in order to not be specific to a particular input method/source)
+// The first record holding an error stops the build and the error is returned
+// Cities are added in the order of their records, followed by any road destinations without a record of their own in the order they are first mentioned
 func BuildWorldFromRecords(records <-chan *WorldRecord) (*World, error) {
 	world := NewWorld()
-	maxMonstersPerCity := 2
+	maxMonstersPerCity := defaultCityCapacity
+	// Road destinations in the order they are mentioned, they may get a record of their own further on
+	var destinations []CityName
 	for {
 		if record, ok := <-records; ok {
-			city := NewCity(record.City, maxMonstersPerCity)
+			if record.Err != nil {
+				// Let the reader finish so that it isn't blocked forever
+				for range records {
+				}
+				return nil, record.Err
+			}
+			city, err := newCityFromRecord(record, maxMonstersPerCity)
+			if err != nil {
+				for range records {
+				}
+				return nil, err
+			}
 			world.AddCity(city)
 			for _, road := range record.Roads {
-				// Check if destination city exists, if not then create it
-				if world.GetCity(road.Destination) == nil {
-					world.AddCity(NewCity(road.Destination, maxMonstersPerCity))
-				}
+				destinations = append(destinations, road.Destination)
 				world.AddRoad(road)
 			}
 		} else {
 			break
 		}
 	}
+	// Check if destination cities exist, if not then create them
+	for _, destination := range destinations {
+		if world.GetCity(destination) == nil {
+			world.AddCity(NewCity(destination, maxMonstersPerCity))
+		}
+	}
 	return world, nil
 }
 
+// newCityFromRecord creates the city described by a record, using its attributes to override the default capacity
+func newCityFromRecord(record *WorldRecord, defaultCapacity int) (*City, error) {
+	capacity := defaultCapacity
+	for _, attr := range record.Attributes {
+		if err := ValidateCityAttribute(attr); err != nil {
+			return nil, fmt.Errorf("line %d: %s: %v", record.Line, record.City, err)
+		}
+		if attr.Key == capacityAttribute {
+			capacity, _ = ParseCapacity(attr.Value)
+		}
+	}
+	city := NewCity(record.City, capacity)
+	city.Attributes = record.Attributes
+	return city, nil
+}
+
+// LoadMode selects how strictly LoadWorld treats problems in world map records
+type LoadMode int
+
+const (
+	// LoadPermissive builds the world like BuildWorldFromRecords, creating cities for undeclared road destinations and accepting any direction
+	LoadPermissive LoadMode = iota
+	// LoadStrict rejects the map if any line can't be parsed, has an unknown direction or another problem, defines a city twice, or has a road to an undeclared city
+	LoadStrict
+	// LoadLenient skips lines which LoadStrict would reject, keeps roads to undeclared cities, and reports all of these as warnings
+	LoadLenient
+)
+
+// ParseLoadMode converts the name of a LoadMode, as used on the command line, to a LoadMode
+func ParseLoadMode(name string) (LoadMode, error) {
+	switch name {
+	case "permissive":
+		return LoadPermissive, nil
+	case "strict":
+		return LoadStrict, nil
+	case "lenient":
+		return LoadLenient, nil
+	}
+	return LoadPermissive, fmt.Errorf("Unknown parse mode %q, expected permissive, strict or lenient", name)
+}
+
+// LoadReport describes how the records of a world map were loaded
+type LoadReport struct {
+	Records  int          // Number of records read
+	Skipped  int          // Number of records left out of the world
+	Warnings []MapProblem // Problems which were tolerated, ordered by line
+}
+
+// LoadError is returned when LoadStrict rejects a map
+type LoadError struct {
+	Problems []MapProblem // Every problem found, ordered by line
+}
+
+func (e *LoadError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		msgs[i] = problem.String()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// LoadWorld generates a World graph from records like BuildWorldFromRecords, treating problems in the records according to mode
+func LoadWorld(records <-chan *WorldRecord, mode LoadMode) (*World, *LoadReport, error) {
+	report := &LoadReport{}
+	if mode == LoadPermissive {
+		w, err := buildWorldFiltered(records, func(record *WorldRecord) bool {
+			report.Records++
+			return true
+		})
+		return w, report, err
+	}
+
+	v := NewMapValidator()
+	var problems []MapProblem
+	w, err := buildWorldFiltered(records, func(record *WorldRecord) bool {
+		report.Records++
+		recordProblems := v.CheckRecord(record)
+		if len(recordProblems) == 0 {
+			return true
+		}
+		problems = append(problems, recordProblems...)
+		report.Skipped++
+		if mode == LoadLenient {
+			// The city of a skipped line only exists if other lines lead to it
+			v.forget(record)
+		}
+		return false
+	})
+	if err != nil {
+		return nil, report, err
+	}
+	problems = append(problems, v.CheckDestinations(w)...)
+	sortProblems(problems)
+
+	if mode == LoadStrict && len(problems) > 0 {
+		return nil, report, &LoadError{Problems: problems}
+	}
+	report.Warnings = problems
+	return w, report, nil
+}
+
 // GetRemainingWorldRecords finds the remaining cities which are reachable and output's their respective records to the channel
 func GetRemainingWorldRecords(w *World) (ch chan *WorldRecord) {
+	return remainingWorldRecords(w, false)
+}
+
+// GetLosslessWorldRecords outputs a record for every remaining city to the channel, including cities with no roads left
+// Records follow the order of the input records and roads keep their original order, so that building a World from them
+// reproduces exactly what is left of w
+func GetLosslessWorldRecords(w *World) (ch chan *WorldRecord) {
+	return remainingWorldRecords(w, true)
+}
+
+// GetWorldSnapshotRecords outputs a record for every city to the channel, including destroyed cities and roads leading to them
+// Records hold each city's state and the names of the monsters in it, or of the monsters which destroyed it
+func GetWorldSnapshotRecords(w *World) (ch chan *WorldRecord) {
+	ch = make(chan *WorldRecord)
+	go func() {
+		defer close(ch)
+		for _, city := range w.GetCities() {
+			record := &WorldRecord{
+				City:       city.Name,
+				Roads:      w.GetRoads(city.Name),
+				Attributes: city.Attributes,
+				Destroyed:  city.Destroyed,
+			}
+			for _, monster := range city.Monsters.GetAll() {
+				record.Monsters = append(record.Monsters, monster.Name())
+			}
+			ch <- record
+		}
+	}()
+	return
+}
+
+// remainingWorldRecords outputs the records of undestroyed cities, skipping cities without roads unless keepIsolated is set
+func remainingWorldRecords(w *World, keepIsolated bool) (ch chan *WorldRecord) {
 	ch = make(chan *WorldRecord)
 	go func() {
 		defer close(ch)
 		for _, city := range w.GetUndestroyedCities() {
-			record := &WorldRecord{City: city.Name}
+			record := &WorldRecord{City: city.Name, Attributes: city.Attributes}
 			possibleDestinations := w.GetRoads(city.Name)
-			if len(possibleDestinations) == 0 {
+			if len(possibleDestinations) == 0 && !keepIsolated {
 				continue
 			}
 			var roads []*Road
@@ -60,7 +314,7 @@ func GetRemainingWorldRecords(w *World) (ch chan *WorldRecord) {
 					roads = append(roads, dest)
 				}
 			}
-			if len(roads) == 0 {
+			if len(roads) == 0 && !keepIsolated {
 				continue
 			}
 			record.Roads = roads
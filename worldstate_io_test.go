@@ -1 +1,154 @@
 package main
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const problematicMap = `Foo north=Bar east=Baz
+Bar south=Foo up=Baz
+Baz west=Foo south=Qux
+Foo west=Bar
+Bee north
+`
+
+func TestLoadWorldStrict(t *testing.T) {
+	_, _, err := LoadWorld(NewCSVReader(strings.NewReader(problematicMap)).ReadAll(), LoadStrict)
+	loadErr, ok := err.(*LoadError)
+	if !ok {
+		t.Fatalf("Expected a *LoadError, got %v", err)
+	}
+	expected := []string{
+		"line 2: Bar: unknown direction \"up\", expected north, south, east or west",
+		"line 3: Baz: road south leads to Qux which has no line of its own",
+		"line 4: Foo: city is already defined on line 1",
+		"line 5: column 5: \"north\": expected a road in the form direction=city",
+	}
+	if len(loadErr.Problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %v", len(expected), loadErr.Problems)
+	}
+	for i, problem := range loadErr.Problems {
+		if problem.String() != expected[i] {
+			t.Errorf("Expected problem %q, got %q", expected[i], problem.String())
+		}
+	}
+}
+
+func TestLoadWorldLenient(t *testing.T) {
+	world, report, err := LoadWorld(NewCSVReader(strings.NewReader(problematicMap)).ReadAll(), LoadLenient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Records != 5 || report.Skipped != 3 || len(report.Warnings) != 5 {
+		t.Errorf("Expected 5 records, 3 skipped and 5 warnings, got %+v", report)
+	}
+	expected := []string{
+		"line 1: Foo: road north leads to Bar which has no line of its own",
+		"line 2: Bar: unknown direction \"up\", expected north, south, east or west",
+		"line 3: Baz: road south leads to Qux which has no line of its own",
+		"line 4: Foo: city is already defined on line 1",
+		"line 5: column 5: \"north\": expected a road in the form direction=city",
+	}
+	for i, warning := range report.Warnings {
+		if i < len(expected) && warning.String() != expected[i] {
+			t.Errorf("Expected warning %q, got %q", expected[i], warning.String())
+		}
+	}
+	// Bar's line is skipped, so it only exists as a destination
+	if len(world.GetRoads("Bar")) != 0 {
+		t.Errorf("Expected Bar's line to be skipped")
+	}
+	// The duplicate definition of Foo doesn't add its roads
+	if len(world.GetRoads("Foo")) != 2 {
+		t.Errorf("Expected Foo to keep the 2 roads from its first line, got %d", len(world.GetRoads("Foo")))
+	}
+	// Roads to undeclared cities are kept
+	if world.GetCity("Qux") == nil {
+		t.Errorf("Expected the undeclared destination Qux to be created")
+	}
+}
+
+func TestLoadWorldPermissive(t *testing.T) {
+	world, report, err := LoadWorld(NewCSVReader(strings.NewReader("Foo north=Bar up=Baz\nFoo west=Bar\n")).ReadAll(), LoadPermissive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Records != 2 || report.Skipped != 0 || len(report.Warnings) != 0 {
+		t.Errorf("Expected permissive loading to accept everything, got %+v", report)
+	}
+	if len(world.GetRoads("Foo")) != 3 {
+		t.Errorf("Expected Foo to have 3 roads, got %d", len(world.GetRoads("Foo")))
+	}
+}
+
+func TestLosslessWorldRecordsRoundTrip(t *testing.T) {
+	file, _ := os.Open("assets/world_map_small.txt")
+	defer file.Close()
+	world, _ := BuildWorldFromRecords(NewCSVReader(file).ReadAll())
+	NewMonsterGame(world, 100, 20, WithSeed(4)).Start()
+
+	var b bytes.Buffer
+	NewCSVWriter(&b).WriteAll(GetLosslessWorldRecords(world))
+	rebuilt, err := BuildWorldFromRecords(NewCSVReader(&b).ReadAll())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remaining := world.GetUndestroyedCities()
+	rebuiltCities := rebuilt.GetCities()
+	if len(remaining) != len(rebuiltCities) {
+		t.Fatalf("Expected %d cities, got %d", len(remaining), len(rebuiltCities))
+	}
+	isolated := 0
+	for i, city := range remaining {
+		if rebuiltCities[i].Name != city.Name {
+			t.Fatalf("Expected %s at position %d, got %s", city.Name, i, rebuiltCities[i].Name)
+		}
+		var roads []Road
+		for _, road := range world.GetRoads(city.Name) {
+			if !world.GetCity(road.Destination).Destroyed {
+				roads = append(roads, *road)
+			}
+		}
+		var rebuiltRoads []Road
+		for _, road := range rebuilt.GetRoads(city.Name) {
+			rebuiltRoads = append(rebuiltRoads, *road)
+		}
+		if !reflect.DeepEqual(roads, rebuiltRoads) {
+			t.Errorf("Expected %s to have roads %v, got %v", city.Name, roads, rebuiltRoads)
+		}
+		if len(roads) == 0 {
+			isolated++
+		}
+	}
+	if isolated == 0 {
+		t.Errorf("Expected the game to leave some isolated cities to check")
+	}
+}
+
+func TestBuildWorldOrdersCitiesByRecord(t *testing.T) {
+	world, _ := BuildWorldFromRecords(NewCSVReader(strings.NewReader("Foo north=Bar east=Qux\nBaz west=Bar\nBar south=Foo\n")).ReadAll())
+	expected := []CityName{"Foo", "Baz", "Bar", "Qux"}
+	for i, city := range world.GetCities() {
+		if city.Name != expected[i] {
+			t.Errorf("Expected %s at position %d, got %s", expected[i], i, city.Name)
+		}
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	cases := map[string]string{
+		"world.json":           "json",
+		"WORLD.JSON":           "json",
+		"assets/world_map.txt": "csv",
+		"":                     "csv",
+	}
+	for path, format := range cases {
+		if FormatFromPath(path) != format {
+			t.Errorf("Expected %q to be read as %s, got %s", path, format, FormatFromPath(path))
+		}
+	}
+}